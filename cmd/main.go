@@ -13,6 +13,6 @@ func main() {
 	rootCmd := dispatch.NewCmdRoot(version)
 
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(dispatch.ExitCode(err))
 	}
 }
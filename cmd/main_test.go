@@ -24,13 +24,18 @@ Usage:
 Available Commands:
   completion  Generate the autocompletion script for the specified shell
   help        Help about any command
+  plan        Send the dispatch events declared by a plan file
   repository  Send a repository dispatch event and watch the resulting GitHub Actions run
+  run         Send the dispatch event declared by a named preset and watch the resulting GitHub Actions run
   workflow    Send a workflow dispatch event and watch the resulting GitHub Actions run
 
 Flags:
-  -h, --help          help for gh
-  -R, --repo string   The targeted repository's full name (default "github.com/mdb/gh-dispatch")
-  -v, --version       version for gh
+  -h, --help                help for gh
+      --org string          Target the non-archived repositories of an organization that have the --topic
+  -R, --repo stringArray    The targeted repository's full name; repeat to target several repositories (default [github.com/mdb/gh-dispatch])
+      --repos-file string   Target the repositories listed in a file, one full name per line, or '-' to read them from stdin
+      --topic string        The topic of the --org repositories to target
+  -v, --version             version for gh
 
 Use "gh [command] --help" for more information about a command.
 `)
@@ -88,11 +93,9 @@ func TestRepositoryAcceptance(t *testing.T) {
 			"--workflow=Hello",
 		},
 		wantOut: []string{
-			"Refreshing run status every 2 seconds. Press Ctrl+C to quit.\n\nhttps://github.com/mdb/gh-dispatch/actions/runs",
-			" hello",
-			"  ✓ Set up job",
-			"  ✓ say-hello",
-			"  ✓ Complete job\n",
+			"Watching https://github.com/mdb/gh-dispatch/actions/runs",
+			"✓ Job hello (ID ",
+			"completed with 'success'",
 		},
 	}, {
 		args: []string{
@@ -104,14 +107,13 @@ func TestRepositoryAcceptance(t *testing.T) {
 			"--workflow=Hello",
 		},
 		wantOut: []string{
-			"Refreshing run status every 2 seconds. Press Ctrl+C to quit.\n\nhttps://github.com/mdb/gh-dispatch/actions/runs",
-			" hello",
-			"  ✓ Set up job",
+			"Watching https://github.com/mdb/gh-dispatch/actions/runs",
+			"X Job hello (ID ",
 			"  X say-hello",
-			"  ✓ Complete job\n",
+			"completed with 'failure'",
 		},
 		wantErr: true,
-		errMsg:  "exit status 1",
+		errMsg:  "exit status 2",
 	}}
 
 	for _, test := range tests {
@@ -155,11 +157,9 @@ func TestWorkflowAcceptance(t *testing.T) {
 			"--workflow=workflow_dispatch.yaml",
 		},
 		wantOut: []string{
-			"Refreshing run status every 2 seconds. Press Ctrl+C to quit.\n\nhttps://github.com/mdb/gh-dispatch/actions/runs",
-			" goodbye",
-			"  ✓ Set up job",
-			"  ✓ say-goodbye",
-			"  ✓ Complete job\n",
+			"Watching https://github.com/mdb/gh-dispatch/actions/runs",
+			"✓ Job goodbye (ID ",
+			"completed with 'success'",
 		},
 	}, {
 		args: []string{
@@ -169,14 +169,13 @@ func TestWorkflowAcceptance(t *testing.T) {
 			"--workflow=workflow_dispatch.yaml",
 		},
 		wantOut: []string{
-			"Refreshing run status every 2 seconds. Press Ctrl+C to quit.\n\nhttps://github.com/mdb/gh-dispatch/actions/runs",
-			" goodbye",
-			"  ✓ Set up job",
+			"Watching https://github.com/mdb/gh-dispatch/actions/runs",
+			"X Job goodbye (ID ",
 			"  X say-goodbye",
-			"  ✓ Complete job\n",
+			"completed with 'failure'",
 		},
 		wantErr: true,
-		errMsg:  "exit status 1",
+		errMsg:  "exit status 2",
 	}}
 
 	for _, test := range tests {
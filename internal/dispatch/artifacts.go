@@ -0,0 +1,161 @@
+package dispatch
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// listArtifacts lists the run's artifacts.
+func listArtifacts(client *cliapi.Client, repo *ghRepo, runID int64) ([]shared.Artifact, error) {
+	perPage := 100
+	page := 1
+	artifacts := []shared.Artifact{}
+
+	for {
+		var result struct {
+			Artifacts []shared.Artifact
+		}
+		path := fmt.Sprintf("repos/%s/actions/runs/%d/artifacts?per_page=%d&page=%d", repo.RepoFullName(), runID, perPage, page)
+		if err := client.REST(repo.RepoHost(), "GET", path, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+
+		artifacts = append(artifacts, result.Artifacts...)
+		if len(result.Artifacts) < perPage {
+			break
+		}
+
+		page++
+	}
+
+	return artifacts, nil
+}
+
+// downloadArtifacts downloads the run's unexpired artifacts whose names match
+// the opts.downloadArtifacts glob, extracting each into a directory named
+// after it within opts.artifactsDir and reporting it to out.
+func downloadArtifacts(opts *dispatchOptions, client *cliapi.Client, out io.Writer, run *shared.Run) error {
+	pattern := opts.downloadArtifacts
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid --download-artifacts pattern %q: %w", pattern, err)
+	}
+
+	artifacts, err := listArtifacts(client, opts.repo, run.ID)
+	if err != nil {
+		return err
+	}
+
+	cs := opts.io.ColorScheme()
+	downloaded := 0
+	for _, artifact := range artifacts {
+		if match, _ := path.Match(pattern, artifact.Name); !match || artifact.Expired {
+			continue
+		}
+
+		dest := filepath.Join(opts.artifactsDir, artifact.Name)
+		if err := downloadArtifact(opts.httpClient, artifact, dest); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "%s Downloaded artifact %s to %s\n", cs.SuccessIcon(), artifact.Name, dest)
+		downloaded++
+	}
+
+	if downloaded == 0 {
+		fmt.Fprintf(out, "%s No artifacts of run %d match %q\n", cs.WarningIcon(), run.ID, pattern)
+	}
+
+	return nil
+}
+
+// downloadArtifact downloads the artifact's zip archive to a temporary file,
+// rather than into memory, and extracts it into dest.
+func downloadArtifact(httpClient *http.Client, artifact shared.Artifact, dest string) error {
+	body, err := openBlob(httpClient, artifact.DownloadURL)
+	if err != nil {
+		return fmt.Errorf("failed to download artifact %s: %w", artifact.Name, err)
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "gh-dispatch-artifact-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to download artifact %s: %w", artifact.Name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download artifact %s: %w", artifact.Name, err)
+	}
+
+	if err := extractZip(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to extract artifact %s: %w", artifact.Name, err)
+	}
+
+	return nil
+}
+
+// extractZip extracts the zip archive at zipPath into dest, refusing entries
+// that would be written outside of it.
+func extractZip(zipPath, dest string) error {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !filepath.IsLocal(file.Name) {
+			return fmt.Errorf("archive entry %s is outside of the destination directory", file.Name)
+		}
+
+		target := filepath.Join(dest, file.Name)
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractZipFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,146 @@
+package dispatch
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadArtifacts(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		httpStubs func(*httpmock.Registry)
+		wantFiles map[string]string
+		wantOut   string
+		wantErr   bool
+		errMsg    string
+	}{
+		{
+			name:    "matching artifacts",
+			pattern: "test-*",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/artifacts"),
+					httpmock.StringResponse(`{
+						"artifacts": [{
+							"name": "test-report",
+							"archive_download_url": "https://api.github.com/repos/OWNER/REPO/actions/artifacts/1/zip"
+						}, {
+							"name": "test-coverage",
+							"archive_download_url": "https://api.github.com/repos/OWNER/REPO/actions/artifacts/2/zip",
+							"expired": true
+						}, {
+							"name": "binary",
+							"archive_download_url": "https://api.github.com/repos/OWNER/REPO/actions/artifacts/3/zip"
+						}]
+					}`))
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/artifacts/1/zip"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"report.txt":      "ok",
+						"unit/report.xml": "<ok/>",
+					})))
+			},
+			wantFiles: map[string]string{
+				"test-report/report.txt":      "ok",
+				"test-report/unit/report.xml": "<ok/>",
+			},
+			wantOut: "✓ Downloaded artifact test-report to {{dir}}/test-report\n",
+		}, {
+			name:    "no matching artifacts",
+			pattern: "*",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/artifacts"),
+					httpmock.StringResponse(`{"artifacts": []}`))
+			},
+			wantOut: "! No artifacts of run 123 match \"*\"\n",
+		}, {
+			name:    "archive entry outside of the directory",
+			pattern: "*",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/artifacts"),
+					httpmock.StringResponse(`{
+						"artifacts": [{
+							"name": "evil",
+							"archive_download_url": "https://api.github.com/repos/OWNER/REPO/actions/artifacts/1/zip"
+						}]
+					}`))
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/artifacts/1/zip"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"../evil.sh": "rm -rf /",
+					})))
+			},
+			wantErr: true,
+			errMsg:  "failed to extract artifact evil: archive entry ../evil.sh is outside of the destination directory",
+		}, {
+			name:      "invalid pattern",
+			pattern:   "[",
+			httpStubs: func(reg *httpmock.Registry) {},
+			wantErr:   true,
+			errMsg:    `invalid --download-artifacts pattern "[": syntax error in pattern`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			defer reg.Verify(t)
+			tt.httpStubs(reg)
+
+			dir := t.TempDir()
+			ios, _, stdout, _ := iostreams.Test()
+			httpClient := &http.Client{Transport: reg}
+			opts := &dispatchOptions{
+				repo:              &ghRepo{Owner: "OWNER", Name: "REPO"},
+				httpClient:        httpClient,
+				io:                ios,
+				downloadArtifacts: tt.pattern,
+				artifactsDir:      dir,
+			}
+
+			err := downloadArtifacts(opts, cliapi.NewClientFromHTTP(httpClient), ios.Out, &shared.Run{ID: 123})
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ReplaceAll(tt.wantOut, "{{dir}}", dir), stdout.String())
+			for name, want := range tt.wantFiles {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				assert.NoError(t, err)
+				assert.Equal(t, want, string(content))
+			}
+		})
+	}
+}
+
+func TestDownloadArtifactsPatternArgument(t *testing.T) {
+	// Without '=', the pattern is a positional argument rather than the
+	// flag's value, which must fail rather than download every artifact.
+	for _, cmd := range []*cobra.Command{NewCmdWorkflow(), NewCmdRepository()} {
+		t.Run(cmd.Name(), func(t *testing.T) {
+			cmd.SetArgs([]string{"--download-artifacts", "test-*", "--dir", "out"})
+			cmd.SetOut(io.Discard)
+			cmd.SetErr(io.Discard)
+
+			err := cmd.Execute()
+			assert.EqualError(t, err, fmt.Sprintf(`unknown command "test-*" for "%s"`, cmd.Name()))
+		})
+	}
+}
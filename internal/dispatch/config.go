@@ -0,0 +1,111 @@
+package dispatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigFileName is the name of a repository's config file, which
+// declares presets in addition to those of the user's config file.
+const repoConfigFileName = ".gh-dispatch.yml"
+
+// config is the content of a gh-dispatch config file.
+type config struct {
+	// Presets are named dispatches run with 'gh dispatch run'.
+	Presets map[string]dispatchSpec `yaml:"presets"`
+}
+
+// userConfigPath returns the path of the user's config file,
+// $XDG_CONFIG_HOME/gh-dispatch/config.yml, defaulting to
+// ~/.config/gh-dispatch/config.yml.
+func userConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "gh-dispatch", "config.yml"), nil
+}
+
+// findRepoConfig returns the path of the repository config file in dir or
+// its closest parent that has one, stopping at the root of the git
+// repository. It returns an empty path if there's none.
+func findRepoConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, repoConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configPaths returns the paths of the config files declaring presets, in
+// increasing order of precedence.
+func configPaths() ([]string, error) {
+	userPath, err := userConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{userPath}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if repoPath := findRepoConfig(wd); repoPath != "" {
+		paths = append(paths, repoPath)
+	}
+
+	return paths, nil
+}
+
+// loadPresets returns the presets declared by the config files at paths,
+// ignoring those that don't exist. A preset replaces any preset of the same
+// name declared by an earlier file.
+func loadPresets(paths []string) (map[string]dispatchSpec, error) {
+	presets := map[string]dispatchSpec{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var c config
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+
+		for name, preset := range c.Presets {
+			presets[name] = preset
+		}
+	}
+
+	return presets, nil
+}
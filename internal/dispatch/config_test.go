@@ -0,0 +1,110 @@
+package dispatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRepoConfig(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	nested := filepath.Join(repo, "a", "b")
+	assert.NoError(t, os.MkdirAll(nested, 0o755))
+	assert.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+
+	// A config file outside of the git repository is ignored.
+	assert.NoError(t, os.WriteFile(filepath.Join(root, repoConfigFileName), nil, 0o600))
+	assert.Equal(t, "", findRepoConfig(nested))
+
+	repoConfig := filepath.Join(repo, "a", repoConfigFileName)
+	assert.NoError(t, os.WriteFile(repoConfig, nil, 0o600))
+	assert.Equal(t, repoConfig, findRepoConfig(nested))
+}
+
+func TestUserConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	path, err := userConfigPath()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/xdg", "gh-dispatch", "config.yml"), path)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/mdb")
+	path, err = userConfigPath()
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/mdb", ".config", "gh-dispatch", "config.yml"), path)
+}
+
+func TestLoadPresets(t *testing.T) {
+	dir := t.TempDir()
+	userConfig := filepath.Join(dir, "config.yml")
+	repoConfig := filepath.Join(dir, repoConfigFileName)
+	emptyConfig := filepath.Join(dir, "empty.yml")
+	invalidConfig := filepath.Join(dir, "invalid.yml")
+
+	assert.NoError(t, os.WriteFile(userConfig, []byte(`presets:
+  hello:
+    repo: OWNER/REPO
+    type: repository
+    event_type: hello
+    workflow: Hello
+  deploy:
+    repo: OWNER/REPO
+    type: workflow
+    workflow: deploy.yaml
+`), 0o600))
+	assert.NoError(t, os.WriteFile(repoConfig, []byte(`presets:
+  deploy:
+    type: workflow
+    workflow: deploy.yaml
+    ref: trunk
+    inputs:
+      environment: staging
+`), 0o600))
+	assert.NoError(t, os.WriteFile(emptyConfig, nil, 0o600))
+	assert.NoError(t, os.WriteFile(invalidConfig, []byte("presets:\n  deploy:\n    workflow_file: deploy.yaml\n"), 0o600))
+
+	tests := []struct {
+		name   string
+		paths  []string
+		want   map[string]dispatchSpec
+		errMsg string
+	}{
+		{
+			name:  "repository presets replace user presets",
+			paths: []string{userConfig, filepath.Join(dir, "missing.yml"), emptyConfig, repoConfig},
+			want: map[string]dispatchSpec{
+				"hello": {
+					Repo:      "OWNER/REPO",
+					Type:      "repository",
+					EventType: "hello",
+					Workflow:  "Hello",
+				},
+				"deploy": {
+					Type:     "workflow",
+					Workflow: "deploy.yaml",
+					Ref:      "trunk",
+					Inputs:   map[string]string{"environment": "staging"},
+				},
+			},
+		}, {
+			name:   "invalid config",
+			paths:  []string{invalidConfig},
+			errMsg: "unable to parse " + invalidConfig + ": yaml: unmarshal errors:\n  line 3: field workflow_file not found in type dispatch.dispatchSpec",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPresets(tt.paths)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// Exit codes returned by gh dispatch.
+const (
+	exitOK    = 0
+	exitError = 1
+
+	// Exit codes indicating the conclusion of a GitHub Actions run that did
+	// not succeed.
+	exitFailure        = 2
+	exitCancelled      = 3
+	exitTimedOut       = 4
+	exitActionRequired = 5
+	exitStartupFailure = 6
+	exitSkipped        = 7
+	exitNeutral        = 8
+	exitStale          = 9
+
+	// exitRunNotFound indicates the dispatch event was sent, but no
+	// resulting GitHub Actions run was found.
+	exitRunNotFound = 10
+
+	// exitWatchTimeout indicates watching the GitHub Actions run exceeded
+	// --timeout, whether or not the run was then cancelled.
+	exitWatchTimeout = 124
+
+	// exitInterrupted indicates watching the GitHub Actions run was
+	// interrupted by Ctrl+C, whether or not the run was then cancelled.
+	exitInterrupted = 130
+)
+
+var conclusionExitCodes = map[shared.Conclusion]int{
+	shared.Failure:        exitFailure,
+	shared.Cancelled:      exitCancelled,
+	shared.TimedOut:       exitTimedOut,
+	shared.ActionRequired: exitActionRequired,
+	shared.StartupFailure: exitStartupFailure,
+	shared.Skipped:        exitSkipped,
+	shared.Neutral:        exitNeutral,
+	shared.Stale:          exitStale,
+}
+
+// exitCoder is implemented by errors associated with a specific exit code.
+type exitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the exit code gh dispatch should exit with for err.
+func ExitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var e exitCoder
+	if errors.As(err, &e) {
+		return e.ExitCode()
+	}
+
+	return exitError
+}
+
+// runConclusionError is returned when a GitHub Actions run completes
+// without succeeding.
+type runConclusionError struct {
+	runID      int64
+	conclusion shared.Conclusion
+}
+
+func (e *runConclusionError) Error() string {
+	return fmt.Sprintf("run %d completed with '%s'", e.runID, e.conclusion)
+}
+
+func (e *runConclusionError) ExitCode() int {
+	if code, ok := conclusionExitCodes[e.conclusion]; ok {
+		return code
+	}
+
+	return exitError
+}
+
+// checkConclusion returns a runConclusionError unless the run succeeded.
+// If neutralAsSuccess is true, skipped and neutral runs are also considered
+// successful.
+func checkConclusion(run *shared.Run, neutralAsSuccess bool) error {
+	switch run.Conclusion {
+	case shared.Success:
+		return nil
+	case shared.Skipped, shared.Neutral:
+		if neutralAsSuccess {
+			return nil
+		}
+	}
+
+	return &runConclusionError{
+		runID:      run.ID,
+		conclusion: run.Conclusion,
+	}
+}
+
+// dispatchesError is returned when any of several dispatches, or their
+// resulting GitHub Actions runs, didn't succeed. It exits with the code of the
+// first such dispatch's error.
+type dispatchesError struct {
+	failed int
+	total  int
+	err    error
+}
+
+func (e *dispatchesError) Error() string {
+	return fmt.Sprintf("%d of %d dispatches did not succeed; first error: %s", e.failed, e.total, e.err)
+}
+
+func (e *dispatchesError) Unwrap() error {
+	return e.err
+}
+
+func (e *dispatchesError) ExitCode() int {
+	return ExitCode(e.err)
+}
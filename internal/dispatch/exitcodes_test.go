@@ -0,0 +1,98 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "no error",
+			want: 0,
+		}, {
+			name: "generic error",
+			err:  errors.New("oops"),
+			want: 1,
+		}, {
+			name: "run not found",
+			err:  &runNotFoundError{event: "workflow_dispatch", workflowID: 456},
+			want: 10,
+		}, {
+			name: "wrapped run conclusion",
+			err:  fmt.Errorf("wrapped: %w", &runConclusionError{runID: 123, conclusion: shared.Cancelled}),
+			want: 3,
+		}, {
+			name: "failure",
+			err:  &runConclusionError{runID: 123, conclusion: shared.Failure},
+			want: 2,
+		}, {
+			name: "timed out",
+			err:  &runConclusionError{runID: 123, conclusion: shared.TimedOut},
+			want: 4,
+		}, {
+			name: "unknown conclusion",
+			err:  &runConclusionError{runID: 123, conclusion: "unknown"},
+			want: 1,
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExitCode(tt.err))
+		})
+	}
+}
+
+func TestCheckConclusion(t *testing.T) {
+	tests := []struct {
+		name             string
+		conclusion       shared.Conclusion
+		neutralAsSuccess bool
+		wantErr          bool
+	}{
+		{
+			name:       "success",
+			conclusion: shared.Success,
+		}, {
+			name:       "failure",
+			conclusion: shared.Failure,
+			wantErr:    true,
+		}, {
+			name:       "skipped",
+			conclusion: shared.Skipped,
+			wantErr:    true,
+		}, {
+			name:             "skipped as success",
+			conclusion:       shared.Skipped,
+			neutralAsSuccess: true,
+		}, {
+			name:             "neutral as success",
+			conclusion:       shared.Neutral,
+			neutralAsSuccess: true,
+		}, {
+			name:             "cancelled despite neutral as success",
+			conclusion:       shared.Cancelled,
+			neutralAsSuccess: true,
+			wantErr:          true,
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConclusion(&shared.Run{ID: 123, Conclusion: tt.conclusion}, tt.neutralAsSuccess)
+
+			if tt.wantErr {
+				assert.EqualError(t, err, fmt.Sprintf("run 123 completed with '%s'", tt.conclusion))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
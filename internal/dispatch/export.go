@@ -0,0 +1,73 @@
+package dispatch
+
+import (
+	"slices"
+	"time"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// runResultFields are the fields available to --json.
+var runResultFields = slices.Concat(shared.SingleRunFields, []string{"annotations", "duration"})
+
+// runResult is the exportable result of a dispatched GitHub Actions run,
+// including its jobs and annotations.
+type runResult struct {
+	*shared.Run
+	annotations []shared.Annotation
+}
+
+// ExportData extends the upstream run export with annotations and with
+// durations, in seconds, for the run and each of its jobs.
+func (r *runResult) ExportData(fields []string) map[string]any {
+	var runFields []string
+	for _, f := range fields {
+		if f != "annotations" && f != "duration" {
+			runFields = append(runFields, f)
+		}
+	}
+
+	data := r.Run.ExportData(runFields)
+
+	if jobs, ok := data["jobs"].([]any); ok {
+		for i, job := range jobs {
+			j := r.Jobs[i]
+			job.(map[string]any)["duration"] = jobDuration(j).Seconds()
+		}
+	}
+
+	for _, f := range fields {
+		switch f {
+		case "annotations":
+			annotations := make([]any, 0, len(r.annotations))
+			for _, a := range r.annotations {
+				annotations = append(annotations, map[string]any{
+					"jobName":   a.JobName,
+					"message":   a.Message,
+					"path":      a.Path,
+					"level":     a.Level,
+					"startLine": a.StartLine,
+				})
+			}
+			data[f] = annotations
+		case "duration":
+			data[f] = r.Duration(time.Now()).Seconds()
+		}
+	}
+
+	return data
+}
+
+// jobDuration returns how long the job ran, or has been running.
+func jobDuration(job shared.Job) time.Duration {
+	if job.StartedAt.IsZero() {
+		return 0
+	}
+
+	end := job.CompletedAt
+	if job.Status != shared.Completed || end.IsZero() {
+		end = time.Now()
+	}
+
+	return end.Sub(job.StartedAt).Round(time.Second)
+}
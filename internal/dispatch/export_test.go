@@ -0,0 +1,82 @@
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunResultExportData(t *testing.T) {
+	startedAt := time.Date(2020, 1, 20, 17, 42, 40, 0, time.UTC)
+	completedAt := startedAt.Add(119 * time.Second)
+
+	result := &runResult{
+		Run: &shared.Run{
+			ID:         123,
+			Status:     shared.Completed,
+			Conclusion: shared.Failure,
+			CreatedAt:  startedAt,
+			UpdatedAt:  completedAt,
+			Jobs: []shared.Job{{
+				ID:          456,
+				Name:        "build",
+				Status:      shared.Completed,
+				Conclusion:  shared.Failure,
+				StartedAt:   startedAt,
+				CompletedAt: completedAt,
+			}},
+		},
+		annotations: []shared.Annotation{{
+			JobName:   "build",
+			Message:   "Process completed with exit code 1.",
+			Path:      ".github",
+			Level:     shared.AnnotationFailure,
+			StartLine: 1,
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   map[string]any
+	}{
+		{
+			name:   "run fields",
+			fields: []string{"databaseId", "conclusion", "duration"},
+			want: map[string]any{
+				"databaseId": int64(123),
+				"conclusion": shared.Failure,
+				"duration":   float64(119),
+			},
+		}, {
+			name:   "annotations",
+			fields: []string{"annotations"},
+			want: map[string]any{
+				"annotations": []any{
+					map[string]any{
+						"jobName":   "build",
+						"message":   "Process completed with exit code 1.",
+						"path":      ".github",
+						"level":     shared.AnnotationFailure,
+						"startLine": 1,
+					},
+				},
+			},
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, result.ExportData(tt.fields))
+		})
+	}
+
+	t.Run("job durations", func(t *testing.T) {
+		data := result.ExportData([]string{"jobs"})
+		jobs := data["jobs"].([]any)
+
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, float64(119), jobs[0].(map[string]any)["duration"])
+	})
+}
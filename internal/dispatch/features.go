@@ -0,0 +1,79 @@
+package dispatch
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	cliapi "github.com/cli/cli/v2/api"
+	ghauth "github.com/cli/go-gh/v2/pkg/auth"
+)
+
+// enterpriseRunDetailsVersion is the first GitHub Enterprise Server version
+// whose workflow dispatch API can return the details of the created run.
+// Earlier versions reject the return_run_details field.
+const enterpriseRunDetailsVersion = "3.21.0"
+
+var (
+	runDetailsSupportMu sync.Mutex
+	// runDetailsSupport caches whether each host supports return_run_details.
+	runDetailsSupport = map[string]bool{}
+)
+
+// supportsRunDetails reports whether the host's workflow dispatch API can
+// return the details of the created run. github.com and ghe.com hosts always
+// can; GitHub Enterprise Server hosts can as of enterpriseRunDetailsVersion.
+// As with the API client, an empty host means github.com.
+func supportsRunDetails(client *cliapi.Client, host string) (bool, error) {
+	if host == "" || !ghauth.IsEnterprise(host) {
+		return true, nil
+	}
+
+	runDetailsSupportMu.Lock()
+	defer runDetailsSupportMu.Unlock()
+
+	if supported, ok := runDetailsSupport[host]; ok {
+		return supported, nil
+	}
+
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+	if err := client.REST(host, "GET", "meta", nil, &meta); err != nil {
+		return false, err
+	}
+
+	supported := versionAtLeast(meta.InstalledVersion, enterpriseRunDetailsVersion)
+	runDetailsSupport[host] = supported
+
+	return supported, nil
+}
+
+// versionAtLeast reports whether the dotted version v is at least min,
+// comparing numeric components and ignoring any pre-release suffix.
+func versionAtLeast(v, min string) bool {
+	parse := func(s string) []int {
+		s, _, _ = strings.Cut(s, "-")
+		parts := []int{}
+		for _, p := range strings.Split(s, ".") {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				break
+			}
+			parts = append(parts, n)
+		}
+		return parts
+	}
+
+	got, want := parse(v), parse(min)
+	for i := range want {
+		if i >= len(got) {
+			return false
+		}
+		if got[i] != want[i] {
+			return got[i] > want[i]
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package dispatch
+
+import (
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportsRunDetails(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		httpStubs func(*httpmock.Registry)
+		want      bool
+	}{
+		{
+			name:      "github.com",
+			host:      "github.com",
+			httpStubs: func(reg *httpmock.Registry) {},
+			want:      true,
+		}, {
+			name: "supported GHES version",
+			host: "new.ghes.example.com",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "api/v3/meta"),
+					httpmock.StringResponse(`{"installed_version": "3.21.1"}`))
+			},
+			want: true,
+		}, {
+			name: "unsupported GHES version",
+			host: "old.ghes.example.com",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "api/v3/meta"),
+					httpmock.StringResponse(`{"installed_version": "3.20.4"}`))
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			defer reg.Verify(t)
+			tt.httpStubs(reg)
+
+			client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+
+			// The result is cached, so the host's version is only fetched once.
+			for range 2 {
+				supported, err := supportsRunDetails(client, tt.host)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, supported)
+			}
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("3.21.0", "3.21.0"))
+	assert.True(t, versionAtLeast("3.22", "3.21.0"))
+	assert.True(t, versionAtLeast("4.0.0", "3.21.0"))
+	assert.True(t, versionAtLeast("3.21.0-rc1", "3.21.0"))
+	assert.False(t, versionAtLeast("3.20.9", "3.21.0"))
+	assert.False(t, versionAtLeast("3.21", "3.21.1"))
+	assert.False(t, versionAtLeast("", "3.21.0"))
+}
@@ -1,5 +1,10 @@
 package dispatch
 
+import (
+	"encoding/base64"
+	"fmt"
+)
+
 var (
 	currentUserResponse string = `{
 		"data": {
@@ -9,6 +14,10 @@ var (
 		}
 	}`
 
+	getRepoResponse string = `{
+		"default_branch": "main"
+	}`
+
 	getWorkflowsResponse string = `{
 		"total_count": 1,
 		"workflows": [{
@@ -19,9 +28,25 @@ var (
 
 	getWorkflowResponse string = `{
 		"id": 456,
-		"name": "foo"
+		"name": "foo",
+		"path": ".github/workflows/workflow.yaml"
 	}`
 
+	workflowContent string = `name: foo
+on:
+  workflow_dispatch:
+    inputs:
+      foo:
+        type: string
+        required: true
+      dispatch_id:
+        type: string
+`
+
+	getWorkflowContentResponse string = fmt.Sprintf(`{
+		"content": "%s"
+	}`, base64.StdEncoding.EncodeToString([]byte(workflowContent)))
+
 	getWorkflowRunsResponse string = `{
 		"total_count": 1,
 		"workflow_runs": [{
@@ -29,6 +54,8 @@ var (
 			"workflow_id": 456,
 			"event": "%s",
 			"name": "foo",
+			"head_branch": "main",
+			"head_sha": "0123456789abcdef0123456789abcdef01234567",
 			"status": "queued",
 			"conclusion": null,
 			"created_at": "2099-01-01T00:00:00Z",
@@ -127,4 +154,31 @@ var (
 			"runner_group_name": "my runner group"
 		}]
 	}`
+
+	getCorrelatedWorkflowRunsResponse string = `{
+		"total_count": 2,
+		"workflow_runs": [{
+			"id": 124,
+			"workflow_id": 456,
+			"event": "%[1]s",
+			"name": "foo",
+			"display_title": "foo",
+			"head_branch": "main",
+			"status": "queued",
+			"conclusion": null,
+			"created_at": "2099-01-01T00:00:00Z",
+			"jobs_url": "https://api.github.com/repos/%[2]s/actions/runs/124/jobs"
+		}, {
+			"id": 123,
+			"workflow_id": 456,
+			"event": "%[1]s",
+			"name": "foo",
+			"display_title": "foo %[3]s",
+			"head_branch": "main",
+			"status": "queued",
+			"conclusion": null,
+			"created_at": "2099-01-01T00:00:00Z",
+			"jobs_url": "https://api.github.com/repos/%[2]s/actions/runs/123/jobs"
+		}]
+	}`
 )
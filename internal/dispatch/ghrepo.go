@@ -6,23 +6,8 @@ import (
 	"strings"
 
 	"github.com/cli/go-gh/v2/pkg/auth"
-	"github.com/spf13/cobra"
 )
 
-func getRepoOption(cmd *cobra.Command) (*ghRepo, error) {
-	r, _ := cmd.Flags().GetString("repo")
-	if r == "" {
-		return nil, errors.New("a --repo must be specified in the [HOST/]OWNER/REPO format")
-	}
-
-	repo, err := newGHRepo(r)
-	if err != nil {
-		return nil, err
-	}
-
-	return repo, nil
-}
-
 // ghRepo satisfies the ghrepo interface.
 // In the context of gh-dispatch, it enables the reuse of
 // functions packaged in the upstream github.com/cli/cli
@@ -71,3 +56,18 @@ func (r ghRepo) RepoHost() string {
 func (r ghRepo) RepoFullName() string {
 	return fmt.Sprintf("%s/%s", r.RepoOwner(), r.RepoName())
 }
+
+// restURL returns the URL of the REST API path on the repository's host. As
+// with the API client, an empty host means github.com.
+func (r ghRepo) restURL(path string) string {
+	host := r.Host
+	if host == "" {
+		host = "github.com"
+	}
+
+	if auth.IsEnterprise(host) {
+		return fmt.Sprintf("https://%s/api/v3/%s", host, path)
+	}
+
+	return fmt.Sprintf("https://api.%s/%s", auth.NormalizeHostname(host), path)
+}
@@ -56,3 +56,22 @@ func TestNewGHRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestRESTURL(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "", want: "https://api.github.com/repos/foo/bar"},
+		{host: "github.com", want: "https://api.github.com/repos/foo/bar"},
+		{host: "acme.ghe.com", want: "https://api.acme.ghe.com/repos/foo/bar"},
+		{host: "other-github.com", want: "https://other-github.com/api/v3/repos/foo/bar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			repo := ghRepo{Owner: "foo", Name: "bar", Host: tt.host}
+			assert.Equal(t, tt.want, repo.restURL("repos/foo/bar"))
+		})
+	}
+}
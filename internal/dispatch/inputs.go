@@ -0,0 +1,215 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/workflow/shared"
+)
+
+// validateWorkflowInputs checks the provided inputs against the inputs
+// declared by the workflow's workflow_dispatch trigger.
+func validateWorkflowInputs(client *cliapi.Client, repo *ghRepo, workflow *shared.Workflow, triggers *workflowTriggers, inputs any) error {
+	var (
+		environments []string
+		err          error
+	)
+	if hasEnvironmentInput(triggers.inputs) {
+		environments, err = getEnvironments(client, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	problems, err := checkInputs(inputs, triggers.inputs, environments)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid inputs for workflow %s:\n  - %s", workflow.Path, strings.Join(problems, "\n  - "))
+	}
+
+	return nil
+}
+
+// promptInputs prompts for a value for each declared input, except for the
+// input named skip.
+func promptInputs(p prompter, client *cliapi.Client, repo *ghRepo, declared []workflowInput, skip string) (map[string]any, error) {
+	var (
+		environments []string
+		err          error
+	)
+	if hasEnvironmentInput(declared) {
+		environments, err = getEnvironments(client, repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inputs := map[string]any{}
+	for _, input := range declared {
+		if input.Name == skip {
+			continue
+		}
+
+		prompt := input.Name
+		if input.Required {
+			prompt += " (required)"
+		}
+		if input.Description != "" {
+			prompt += ": " + input.Description
+		}
+
+		var answer string
+		switch input.Type {
+		case "boolean":
+			var confirmed bool
+			confirmed, err = p.Confirm(prompt, input.Default == "true")
+			answer = strconv.FormatBool(confirmed)
+		case "choice", "environment":
+			options := input.Options
+			if input.Type == "environment" {
+				options = environments
+			}
+
+			var selected int
+			selected, err = p.Select(prompt, input.Default, options)
+			if err == nil {
+				answer = options[selected]
+			}
+		default:
+			answer, err = p.Input(prompt, input.Default)
+			for err == nil && input.Required && answer == "" {
+				answer, err = p.Input(prompt, input.Default)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		inputs[input.Name] = answer
+	}
+
+	return inputs, nil
+}
+
+func hasEnvironmentInput(inputs []workflowInput) bool {
+	for _, input := range inputs {
+		if input.Type == "environment" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkInputs returns a description of each way in which the provided inputs
+// don't satisfy the declared inputs.
+func checkInputs(inputs any, declared []workflowInput, environments []string) ([]string, error) {
+	provided := map[string]any{}
+	if inputs != nil {
+		m, ok := inputs.(map[string]any)
+		if !ok {
+			return nil, errors.New("inputs must be a JSON object")
+		}
+		provided = m
+	}
+
+	var problems []string
+	known := map[string]bool{}
+
+	for _, input := range declared {
+		known[input.Name] = true
+
+		value, ok := provided[input.Name]
+		if !ok {
+			if input.Required && input.Default == "" {
+				problems = append(problems, fmt.Sprintf("missing required input '%s'", input.Name))
+			}
+			continue
+		}
+
+		s, ok := value.(string)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("input '%s' must be a JSON string, such as %q", input.Name, fmt.Sprint(value)))
+			continue
+		}
+
+		switch input.Type {
+		case "boolean":
+			if s != "true" && s != "false" {
+				problems = append(problems, fmt.Sprintf("input '%s' must be \"true\" or \"false\"; got %q", input.Name, s))
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				problems = append(problems, fmt.Sprintf("input '%s' must be a number; got %q", input.Name, s))
+			}
+		case "choice":
+			if !slices.Contains(input.Options, s) {
+				problems = append(problems, fmt.Sprintf("input '%s' must be one of %s; got %q", input.Name, quoteAll(input.Options), s))
+			}
+		case "environment":
+			if !slices.Contains(environments, s) {
+				problems = append(problems, fmt.Sprintf("input '%s' must be one of the repository's environments %s; got %q", input.Name, quoteAll(environments), s))
+			}
+		}
+	}
+
+	var unknown []string
+	for name := range provided {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown input '%s'", name))
+	}
+
+	return problems, nil
+}
+
+func getEnvironments(client *cliapi.Client, repo *ghRepo) ([]string, error) {
+	perPage := 100
+	page := 1
+	environments := []string{}
+
+	for {
+		var result struct {
+			Environments []struct {
+				Name string
+			}
+		}
+		path := fmt.Sprintf("repos/%s/environments?per_page=%d&page=%d", repo.RepoFullName(), perPage, page)
+		if err := client.REST(repo.RepoHost(), "GET", path, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to get environments: %w", err)
+		}
+
+		for _, env := range result.Environments {
+			environments = append(environments, env.Name)
+		}
+		if len(result.Environments) < perPage {
+			break
+		}
+
+		page++
+	}
+
+	return environments, nil
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,160 @@
+package dispatch
+
+import (
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckInputs(t *testing.T) {
+	declared := []workflowInput{{
+		Name:     "name",
+		Type:     "string",
+		Required: true,
+	}, {
+		Name:    "force_fail",
+		Type:    "boolean",
+		Default: "false",
+	}, {
+		Name: "count",
+		Type: "number",
+	}, {
+		Name:    "level",
+		Type:    "choice",
+		Options: []string{"debug", "info"},
+	}, {
+		Name: "env",
+		Type: "environment",
+	}}
+	environments := []string{"staging", "production"}
+
+	tests := []struct {
+		name         string
+		inputs       any
+		wantProblems []string
+		wantErr      bool
+		errMsg       string
+	}{
+		{
+			name: "valid inputs",
+			inputs: map[string]any{
+				"name":       "Mike",
+				"force_fail": "true",
+				"count":      "3",
+				"level":      "info",
+				"env":        "staging",
+			},
+		}, {
+			name:         "no inputs",
+			inputs:       nil,
+			wantProblems: []string{"missing required input 'name'"},
+		}, {
+			name: "invalid inputs",
+			inputs: map[string]any{
+				"name":       "Mike",
+				"force_fail": false,
+				"count":      "three",
+				"level":      "trace",
+				"env":        "dev",
+				"extra":      "foo",
+			},
+			wantProblems: []string{
+				`input 'force_fail' must be a JSON string, such as "false"`,
+				`input 'count' must be a number; got "three"`,
+				`input 'level' must be one of ["debug", "info"]; got "trace"`,
+				`input 'env' must be one of the repository's environments ["staging", "production"]; got "dev"`,
+				"unknown input 'extra'",
+			},
+		}, {
+			name: "invalid boolean",
+			inputs: map[string]any{
+				"name":       "Mike",
+				"force_fail": "yes",
+			},
+			wantProblems: []string{`input 'force_fail' must be "true" or "false"; got "yes"`},
+		}, {
+			name:    "non-object inputs",
+			inputs:  []any{"foo"},
+			wantErr: true,
+			errMsg:  "inputs must be a JSON object",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := checkInputs(tt.inputs, declared, environments)
+
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantProblems, problems)
+			}
+		})
+	}
+}
+
+func TestPromptInputs(t *testing.T) {
+	declared := []workflowInput{{
+		Name:        "name",
+		Description: "Who to greet",
+		Type:        "string",
+		Required:    true,
+	}, {
+		Name:    "force_fail",
+		Type:    "boolean",
+		Default: "true",
+	}, {
+		Name:    "level",
+		Type:    "choice",
+		Default: "info",
+		Options: []string{"info", "debug"},
+	}, {
+		Name: "env",
+		Type: "environment",
+	}, {
+		Name: "dispatch_id",
+		Type: "string",
+	}}
+
+	reg := &httpmock.Registry{}
+	defer reg.Verify(t)
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/environments"),
+		httpmock.StringResponse(`{"environments": [{"name": "staging"}, {"name": "production"}]}`))
+
+	p := ghprompter.NewMock(t)
+	// A required input is prompted for again until a value is entered.
+	p.RegisterInput("name (required): Who to greet", func(_, _ string) (string, error) {
+		return "", nil
+	})
+	p.RegisterInput("name (required): Who to greet", func(_, _ string) (string, error) {
+		return "Mike", nil
+	})
+	p.RegisterConfirm("force_fail", func(_ string, defaultValue bool) (bool, error) {
+		assert.True(t, defaultValue)
+		return false, nil
+	})
+	p.RegisterSelect("level", []string{"info", "debug"}, func(_, defaultValue string, _ []string) (int, error) {
+		assert.Equal(t, "info", defaultValue)
+		return 1, nil
+	})
+	p.RegisterSelect("env", []string{"staging", "production"}, func(_, _ string, _ []string) (int, error) {
+		return 1, nil
+	})
+
+	client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+	repo := &ghRepo{Owner: "OWNER", Name: "REPO"}
+
+	inputs, err := promptInputs(p, client, repo, declared, "dispatch_id")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":       "Mike",
+		"force_fail": "false",
+		"level":      "debug",
+		"env":        "production",
+	}, inputs)
+}
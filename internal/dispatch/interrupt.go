@@ -0,0 +1,190 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/iostreams"
+)
+
+// errInterrupted is returned when watching runs is interrupted by Ctrl+C.
+var errInterrupted = errors.New("interrupted")
+
+// notifyInterrupt relays SIGINT to c until the returned function is called.
+var notifyInterrupt = func(c chan<- os.Signal) func() {
+	signal.Notify(c, os.Interrupt)
+
+	return func() { signal.Stop(c) }
+}
+
+// startAlternateScreenBuffer switches to the alternate screen buffer. As ios
+// exits on SIGINT while the buffer is active, the signal is relayed to
+// interrupts instead, leaving the caller to restore the buffer.
+func startAlternateScreenBuffer(ios *iostreams.IOStreams, interrupts chan<- os.Signal) {
+	ios.StartAlternateScreenBuffer()
+	signal.Reset(os.Interrupt)
+	notifyInterrupt(interrupts)
+}
+
+// watchStops are the events that stop watching runs before they complete.
+type watchStops struct {
+	// interrupts relays SIGINT.
+	interrupts chan os.Signal
+	// timeout fires once watching times out. It's nil if watching never
+	// times out.
+	timeout <-chan time.Time
+}
+
+// newWatchStops returns watchStops relaying SIGINT, until the returned
+// function is called, and timing out after timeout, unless it's 0.
+func newWatchStops(timeout time.Duration) (*watchStops, func()) {
+	stops := &watchStops{
+		interrupts: make(chan os.Signal, 1),
+	}
+	if timeout > 0 {
+		stops.timeout = time.After(timeout)
+	}
+
+	return stops, notifyInterrupt(stops.interrupts)
+}
+
+// sleep waits for d, returning errInterrupted or errWatchTimeout if watching
+// is interrupted or times out before it elapses.
+func (s *watchStops) sleep(d time.Duration) error {
+	select {
+	case <-s.interrupts:
+		return errInterrupted
+	case <-s.timeout:
+		return errWatchTimeout
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// formatRunIDs returns "run 1" or "runs 1, 2" for the run IDs.
+func formatRunIDs(runIDs []int64) string {
+	ids := make([]string, len(runIDs))
+	for i, id := range runIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+
+	if len(ids) > 1 {
+		return "runs " + strings.Join(ids, ", ")
+	}
+
+	return "run " + strings.Join(ids, ", ")
+}
+
+// interruptedError is returned when watching runs is interrupted, whether or
+// not the runs were then cancelled.
+type interruptedError struct {
+	runIDs    []int64
+	cancelled bool
+}
+
+func (e *interruptedError) Error() string {
+	if len(e.runIDs) == 0 {
+		return "interrupted"
+	}
+
+	if e.cancelled {
+		return fmt.Sprintf("interrupted; cancelled %s", formatRunIDs(e.runIDs))
+	}
+
+	return fmt.Sprintf("interrupted; %s left running", formatRunIDs(e.runIDs))
+}
+
+func (e *interruptedError) ExitCode() int {
+	return exitInterrupted
+}
+
+// handleInterrupt cancels the runs, which were being watched when interrupted,
+// if --cancel-on-interrupt is set or the user confirms it.
+func handleInterrupt(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run, stops *watchStops) error {
+	interrupted := &interruptedError{}
+	for _, run := range runs {
+		interrupted.runIDs = append(interrupted.runIDs, run.ID)
+	}
+
+	cancel := opts.cancelOnInterrupt
+	if !cancel && opts.prompter != nil && opts.io.CanPrompt() {
+		prompt := fmt.Sprintf("Cancel run %d?", runs[0].ID)
+		if len(runs) > 1 {
+			prompt = fmt.Sprintf("Cancel %d runs?", len(runs))
+		}
+
+		// Interrupting the prompt declines it, as does the default, so
+		// that the run isn't cancelled by a stray Enter.
+		cancel, _ = opts.prompter.Confirm(prompt, false)
+	}
+
+	if !cancel {
+		return interrupted
+	}
+
+	if err := cancelRuns(opts, client, runs, stops); err != nil {
+		return err
+	}
+	interrupted.cancelled = true
+
+	return interrupted
+}
+
+// cancelRuns cancels the runs and waits for them to complete, logging their
+// conclusions. Interrupting the wait leaves the remaining runs cancelling.
+func cancelRuns(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run, stops *watchStops) error {
+	cs := opts.io.ColorScheme()
+	out := messageWriter(opts)
+
+	for _, run := range runs {
+		err := client.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/actions/runs/%d/cancel", opts.repo.RepoFullName(), run.ID), nil, nil)
+		if isConflict(err) {
+			// The run completed before it could be cancelled; its conclusion
+			// is logged below.
+			fmt.Fprintf(out, "Run %d already completed\n", run.ID)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to cancel run %d: %w", run.ID, err)
+		}
+		fmt.Fprintf(out, "Cancelling run %d...\n", run.ID)
+	}
+
+	for _, run := range runs {
+		for {
+			var err error
+			run, err = shared.GetRun(client, opts.repo, fmt.Sprintf("%d", run.ID), 0)
+			if err != nil {
+				return fmt.Errorf("failed to get run: %w", err)
+			}
+
+			if run.Status == shared.Completed {
+				logCompletion(out, cs, run)
+				break
+			}
+
+			if err := stops.sleep(time.Duration(opts.refreshInterval()) * time.Second); err != nil {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// isConflict returns whether err is an API error reporting a conflict, such as
+// cancelling a run that has already completed.
+func isConflict(err error) bool {
+	var httpErr cliapi.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+
+	return httpErr.StatusCode == http.StatusConflict
+}
@@ -0,0 +1,163 @@
+package dispatch
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderResultInterrupted(t *testing.T) {
+	defer func(notify func(chan<- os.Signal) func()) { notifyInterrupt = notify }(notifyInterrupt)
+	notifyInterrupt = func(c chan<- os.Signal) func() {
+		select {
+		case c <- os.Interrupt:
+		default:
+		}
+
+		return func() {}
+	}
+
+	tests := []struct {
+		name              string
+		tty               bool
+		cancelOnInterrupt bool
+		confirm           bool
+		httpStubs         func(*httpmock.Registry)
+		wantOut           string
+		wantOutContains   string
+		errMsg            string
+	}{
+		{
+			name:      "left running when not interactive",
+			httpStubs: registerInProgressRunStubs,
+			wantOut:   "Watching https://github.com/OWNER/REPO/actions/runs/123\n* Run foo (123) in_progress\n",
+			errMsg:    "interrupted; run 123 left running",
+		}, {
+			name:              "cancelled with --cancel-on-interrupt",
+			cancelOnInterrupt: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerInProgressRunStubs(reg)
+				registerCancelRunStubs(reg)
+			},
+			wantOut: "Watching https://github.com/OWNER/REPO/actions/runs/123\n* Run foo (123) in_progress\nCancelling run 123...\nX Run foo (123) completed with 'cancelled'\n",
+			errMsg:  "interrupted; cancelled run 123",
+		}, {
+			name:              "already completed when cancelled",
+			cancelOnInterrupt: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerInProgressRunStubs(reg)
+				reg.Register(
+					httpmock.REST("POST", "repos/OWNER/REPO/actions/runs/123/cancel"),
+					httpmock.StatusStringResponse(http.StatusConflict, `{"message": "Cannot cancel a workflow run that is completed."}`))
+				registerRunStubs(reg, "completed", "success")
+			},
+			wantOut: "Watching https://github.com/OWNER/REPO/actions/runs/123\n* Run foo (123) in_progress\nRun 123 already completed\n✓ Run foo (123) completed with 'success'\n",
+			errMsg:  "interrupted; cancelled run 123",
+		}, {
+			name:    "cancelled once confirmed",
+			tty:     true,
+			confirm: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerInProgressRunStubs(reg)
+				registerCancelRunStubs(reg)
+			},
+			wantOutContains: "Cancelling run 123...\nX Run foo (123) completed with 'cancelled'\n",
+			errMsg:          "interrupted; cancelled run 123",
+		}, {
+			name:      "left running once declined",
+			tty:       true,
+			httpStubs: registerInProgressRunStubs,
+			errMsg:    "interrupted; run 123 left running",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+			ios.SetStdoutTTY(tt.tty)
+			ios.SetStdinTTY(tt.tty)
+			ios.SetAlternateScreenBufferEnabled(false)
+
+			var p prompter
+			if tt.tty {
+				mock := ghprompter.NewMock(t)
+				mock.RegisterConfirm("Cancel run 123?", func(_ string, defaultValue bool) (bool, error) {
+					assert.False(t, defaultValue)
+					return tt.confirm, nil
+				})
+				p = mock
+			}
+
+			opts := &dispatchOptions{
+				repo:              &ghRepo{Owner: "OWNER", Name: "REPO"},
+				httpClient:        &http.Client{Transport: reg},
+				io:                ios,
+				prompter:          p,
+				cancelOnInterrupt: tt.cancelOnInterrupt,
+			}
+
+			client := cliapi.NewClientFromHTTP(opts.httpClient)
+			err := renderResult(opts, client, &shared.Run{ID: 123, WorkflowID: 456})
+			assert.EqualError(t, err, tt.errMsg)
+			assert.Equal(t, exitInterrupted, ExitCode(err))
+
+			if tt.wantOutContains != "" {
+				assert.Contains(t, stdout.String(), tt.wantOutContains)
+			} else if !tt.tty {
+				assert.Equal(t, tt.wantOut, stdout.String())
+			}
+
+			reg.Verify(t)
+		})
+	}
+}
+
+func TestInterruptedError(t *testing.T) {
+	err := &interruptedError{runIDs: []int64{123, 124}, cancelled: true}
+	assert.EqualError(t, err, "interrupted; cancelled runs 123, 124")
+	assert.Equal(t, 130, ExitCode(err))
+}
+
+// registerRunStubs registers the stubs fetching run 123 of workflow 456.
+func registerRunStubs(reg *httpmock.Registry, status, conclusion string) {
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123"),
+		httpmock.StringResponse(`{
+			"id": 123,
+			"workflow_id": 456,
+			"event": "workflow_dispatch",
+			"status": "`+status+`",
+			"conclusion": "`+conclusion+`",
+			"jobs_url": "https://api.github.com/repos/OWNER/REPO/actions/runs/123/jobs"
+		}`))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows/456"),
+		httpmock.StringResponse(getWorkflowResponse))
+}
+
+// registerInProgressRunStubs registers the stubs fetching run 123 while it's
+// in progress, along with its jobs.
+func registerInProgressRunStubs(reg *httpmock.Registry) {
+	registerRunStubs(reg, "in_progress", "")
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/jobs"),
+		httpmock.StringResponse(`{"total_count": 0, "jobs": []}`))
+}
+
+// registerCancelRunStubs registers the stubs cancelling run 123 and fetching
+// it once cancelled.
+func registerCancelRunStubs(reg *httpmock.Registry) {
+	reg.Register(
+		httpmock.REST("POST", "repos/OWNER/REPO/actions/runs/123/cancel"),
+		httpmock.StatusStringResponse(http.StatusAccepted, "{}"))
+	registerRunStubs(reg, "completed", "cancelled")
+}
@@ -0,0 +1,259 @@
+package dispatch
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/iostreams"
+)
+
+// errLogNotFound is returned when a log isn't available, such as that of a
+// job that has yet to start.
+var errLogNotFound = errors.New("log not found")
+
+var logTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z `)
+
+// getLog fetches the log at the REST API path on the repository's host.
+func getLog(httpClient *http.Client, repo *ghRepo, path string) ([]byte, error) {
+	content, err := getBlob(httpClient, repo.restURL(path))
+	if errors.Is(err, errBlobNotFound) {
+		return nil, errLogNotFound
+	}
+
+	return content, err
+}
+
+// errBlobNotFound is returned when a blob, such as a log or an artifact,
+// doesn't exist.
+var errBlobNotFound = errors.New("not found")
+
+// getBlob fetches the content at url, following any redirect to its storage.
+func getBlob(httpClient *http.Client, url string) ([]byte, error) {
+	body, err := openBlob(httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// openBlob requests the content at url, following any redirect to its storage,
+// and returns the response body for the caller to read and close.
+func openBlob(httpClient *http.Client, url string) (io.ReadCloser, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, errBlobNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, cliapi.HandleHTTPError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// getJobLog fetches the job's log lines, without their timestamps.
+func getJobLog(httpClient *http.Client, repo *ghRepo, jobID int64) ([]string, error) {
+	content, err := getLog(httpClient, repo, fmt.Sprintf("repos/%s/actions/jobs/%d/logs", repo.RepoFullName(), jobID))
+	if err != nil {
+		return nil, err
+	}
+
+	return logLines(bytes.NewReader(content))
+}
+
+// logLines reads the lines of a log, stripping their timestamps.
+func logLines(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, logTimestamp.ReplaceAllString(scanner.Text(), ""))
+	}
+
+	return lines, scanner.Err()
+}
+
+// logTailer follows the logs of a run's jobs. GitHub serves a job's log only
+// once the job completes, so each job's log is written in full as soon as it's
+// available rather than line by line.
+type logTailer struct {
+	httpClient *http.Client
+	repo       *ghRepo
+	// done records the jobs whose logs have been written.
+	done map[int64]bool
+}
+
+func newLogTailer(httpClient *http.Client, repo *ghRepo) *logTailer {
+	return &logTailer{
+		httpClient: httpClient,
+		repo:       repo,
+		done:       map[int64]bool{},
+	}
+}
+
+// tail writes the log of each newly completed job, prefixed with the job's
+// name.
+func (t *logTailer) tail(out io.Writer, cs *iostreams.ColorScheme, jobs []shared.Job) error {
+	for _, job := range jobs {
+		if t.done[job.ID] || job.Status != shared.Completed {
+			continue
+		}
+
+		lines, err := getJobLog(t.httpClient, t.repo, job.ID)
+		if errors.Is(err, errLogNotFound) {
+			// The log of a job that just completed may not be available yet.
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to get log of job %s: %w", job.Name, err)
+		}
+
+		for _, line := range lines {
+			fmt.Fprintf(out, "%s %s\n", cs.Gray(job.Name+" |"), line)
+		}
+		t.done[job.ID] = true
+	}
+
+	return nil
+}
+
+// stepLog is the log of a failed step or, when the step's own log isn't
+// available, of its job.
+type stepLog struct {
+	job   shared.Job
+	step  *shared.Step
+	lines []string
+}
+
+// getFailedStepLogs fetches the logs of the failed steps of the run's failed
+// jobs from the run's logs archive. The run's jobs must be populated.
+func getFailedStepLogs(httpClient *http.Client, repo *ghRepo, run *shared.Run) ([]stepLog, error) {
+	// Spare downloading the logs when there are no failed jobs to show.
+	if !slices.ContainsFunc(run.Jobs, func(job shared.Job) bool {
+		return shared.IsFailureState(job.Conclusion)
+	}) {
+		return nil, nil
+	}
+
+	content, err := getLog(httpClient, repo, fmt.Sprintf("repos/%s/actions/runs/%d/logs", repo.RepoFullName(), run.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run logs: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run logs: %w", err)
+	}
+
+	logs := []stepLog{}
+	for _, job := range run.Jobs {
+		if !shared.IsFailureState(job.Conclusion) {
+			continue
+		}
+
+		name := regexp.QuoteMeta(logFileJobName(job.Name))
+		found := false
+		for _, step := range job.Steps {
+			if !shared.IsFailureState(step.Conclusion) {
+				continue
+			}
+
+			lines, err := readArchiveLog(archive, regexp.MustCompile(fmt.Sprintf(`^%s/%d_.*\.txt$`, name, step.Number)))
+			if errors.Is(err, errLogNotFound) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			logs = append(logs, stepLog{job: job, step: &step, lines: lines})
+			found = true
+		}
+		if found {
+			continue
+		}
+
+		// Newer archives only contain a log per job.
+		lines, err := readArchiveLog(archive, regexp.MustCompile(fmt.Sprintf(`^-?\d+_%s\.txt$`, name)))
+		if errors.Is(err, errLogNotFound) {
+			lines, err = getJobLog(httpClient, repo, job.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get log of job %s: %w", job.Name, err)
+		}
+
+		logs = append(logs, stepLog{job: job, lines: lines})
+	}
+
+	return logs, nil
+}
+
+// readArchiveLog reads the lines of the first log in the archive whose name
+// matches re.
+func readArchiveLog(archive *zip.Reader, re *regexp.Regexp) ([]string, error) {
+	for _, file := range archive.File {
+		if !re.MatchString(file.Name) {
+			continue
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return logLines(f)
+	}
+
+	return nil, errLogNotFound
+}
+
+// logFileJobName returns the name of the job as it appears in the file names
+// of a run's logs archive, which omit '/' and ':' characters and truncate the
+// name to 90 UTF-16 code units.
+func logFileJobName(name string) string {
+	name = strings.NewReplacer("/", "", ":", "").Replace(name)
+	encoded := utf16.Encode([]rune(name))
+	if len(encoded) > 90 {
+		name = string(utf16.Decode(encoded[:90]))
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// printStepLogs writes each log under a job and step header, truncated to
+// its last maxLines lines unless maxLines is 0.
+func printStepLogs(out io.Writer, cs *iostreams.ColorScheme, logs []stepLog, maxLines int) {
+	for _, log := range logs {
+		header := log.job.Name
+		if log.step != nil {
+			header += " / " + log.step.Name
+		}
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, cs.Bold(header))
+
+		lines := log.lines
+		if maxLines > 0 && len(lines) > maxLines {
+			fmt.Fprintln(out, cs.Grayf("... %d earlier lines omitted", len(lines)-maxLines))
+			lines = lines[len(lines)-maxLines:]
+		}
+		for _, line := range lines {
+			fmt.Fprintln(out, line)
+		}
+	}
+}
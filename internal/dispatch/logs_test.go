@@ -0,0 +1,264 @@
+package dispatch
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTailer(t *testing.T) {
+	reg := &httpmock.Registry{}
+	defer reg.Verify(t)
+	// GitHub serves a job's log only once it completes.
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/jobs/2/logs"),
+		httpmock.StatusStringResponse(404, "Not Found"))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/jobs/1/logs"),
+		httpmock.StringResponse("2020-01-20T17:42:40.1234567Z Cloning\n2020-01-20T17:42:41.1234567Z Building\n"))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/jobs/2/logs"),
+		httpmock.StringResponse("2020-01-20T17:42:40.1234567Z Linting\n"))
+
+	ios, _, _, _ := iostreams.Test()
+	tailer := newLogTailer(&http.Client{Transport: reg}, &ghRepo{Owner: "OWNER", Name: "REPO"})
+	out := &bytes.Buffer{}
+
+	// Jobs still in progress or queued aren't fetched.
+	jobs := []shared.Job{
+		{ID: 1, Name: "build", Status: shared.InProgress},
+		{ID: 2, Name: "lint", Status: shared.Completed},
+		{ID: 3, Name: "deploy", Status: shared.Queued},
+	}
+	assert.NoError(t, tailer.tail(out, ios.ColorScheme(), jobs))
+	assert.Equal(t, "", out.String())
+
+	jobs = []shared.Job{
+		{ID: 1, Name: "build", Status: shared.Completed},
+		{ID: 2, Name: "lint", Status: shared.Completed},
+	}
+	assert.NoError(t, tailer.tail(out, ios.ColorScheme(), jobs))
+
+	// Jobs whose logs were written aren't fetched again.
+	assert.NoError(t, tailer.tail(out, ios.ColorScheme(), jobs))
+
+	assert.Equal(t, `build | Cloning
+build | Building
+lint | Linting
+`, out.String())
+}
+
+func createLogsArchive(t *testing.T, files map[string]string) []byte {
+	archive := &bytes.Buffer{}
+	zw := zip.NewWriter(archive)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+
+	return archive.Bytes()
+}
+
+func TestGetFailedStepLogs(t *testing.T) {
+	reg := &httpmock.Registry{}
+	defer reg.Verify(t)
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/logs"),
+		httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+			"build/1_Checkout.txt": "2020-01-20T17:42:40.1234567Z Checked out\n",
+			"build/2_Test.txt":     "2020-01-20T17:42:40.1234567Z Testing\n2020-01-20T17:42:41.1234567Z FAIL\n",
+			"0_build.txt":          "2020-01-20T17:42:40.1234567Z Checked out\n",
+			"1_lint.txt":           "2020-01-20T17:42:40.1234567Z Linting\n2020-01-20T17:42:41.1234567Z 3 issues\n",
+		})))
+
+	run := &shared.Run{
+		ID: 123,
+		Jobs: []shared.Job{{
+			ID:         1,
+			Name:       "build",
+			Conclusion: shared.Failure,
+			Steps: shared.Steps{
+				{Name: "Checkout", Number: 1, Conclusion: shared.Success},
+				{Name: "Test", Number: 2, Conclusion: shared.Failure},
+			},
+		}, {
+			ID:         2,
+			Name:       "lint",
+			Conclusion: shared.Failure,
+			Steps: shared.Steps{
+				{Name: "Lint", Number: 1, Conclusion: shared.Failure},
+			},
+		}, {
+			ID:         3,
+			Name:       "docs",
+			Conclusion: shared.Success,
+		}},
+	}
+
+	logs, err := getFailedStepLogs(&http.Client{Transport: reg}, &ghRepo{Owner: "OWNER", Name: "REPO"}, run)
+	assert.NoError(t, err)
+
+	ios, _, _, _ := iostreams.Test()
+	out := &bytes.Buffer{}
+	printStepLogs(out, ios.ColorScheme(), logs, 1)
+
+	assert.Equal(t, `
+build / Test
+... 1 earlier lines omitted
+FAIL
+
+lint
+... 1 earlier lines omitted
+3 issues
+`, out.String())
+}
+
+func TestPrintFailedStepLogs(t *testing.T) {
+	failedRun := &shared.Run{
+		ID:         123,
+		Conclusion: shared.Failure,
+		Jobs: []shared.Job{{
+			ID:         1,
+			Name:       "build",
+			Conclusion: shared.Failure,
+			Steps: shared.Steps{
+				{Name: "Test", Number: 1, Conclusion: shared.Failure},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		opts      dispatchOptions
+		run       *shared.Run
+		httpStubs func(*httpmock.Registry)
+		wantOut   string
+		wantErr   string
+	}{
+		{
+			name: "successful run",
+			opts: dispatchOptions{failedLogs: true},
+			run: &shared.Run{
+				ID:         123,
+				Conclusion: shared.Success,
+			},
+			httpStubs: func(reg *httpmock.Registry) {},
+		}, {
+			name:      "failed run without failed logs",
+			run:       failedRun,
+			httpStubs: func(reg *httpmock.Registry) {},
+		}, {
+			name: "failed run with failed logs",
+			opts: dispatchOptions{failedLogs: true, failedLogLines: 1},
+			run:  failedRun,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/logs"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"build/1_Test.txt": "Testing\nFAIL\n",
+					})))
+			},
+			wantOut: `
+build / Test
+... 1 earlier lines omitted
+FAIL
+`,
+		}, {
+			name: "failed run with full logs",
+			opts: dispatchOptions{logs: true, failedLogs: true, failedLogLines: 1},
+			run:  failedRun,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/logs"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"build/1_Test.txt": "Testing\nFAIL\n",
+					})))
+			},
+			wantOut: `
+build / Test
+Testing
+FAIL
+`,
+		}, {
+			name: "failed run without fetched jobs",
+			opts: dispatchOptions{failedLogs: true, failedLogLines: 1},
+			run: &shared.Run{
+				ID:         123,
+				Conclusion: shared.Failure,
+				JobsURL:    "https://api.github.com/repos/OWNER/REPO/actions/runs/123/jobs",
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/jobs"),
+					httpmock.StringResponse(`{"jobs": [{
+						"id": 1,
+						"name": "build",
+						"conclusion": "failure",
+						"steps": [{"name": "Test", "number": 1, "conclusion": "failure"}]
+					}]}`))
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/logs"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"build/1_Test.txt": "Testing\nFAIL\n",
+					})))
+			},
+			wantOut: `
+build / Test
+... 1 earlier lines omitted
+FAIL
+`,
+		}, {
+			name: "failed run without failed jobs",
+			opts: dispatchOptions{failedLogs: true},
+			run: &shared.Run{
+				ID:         123,
+				Conclusion: shared.Failure,
+				Jobs:       []shared.Job{{ID: 1, Name: "build", Conclusion: shared.Cancelled}},
+			},
+			httpStubs: func(reg *httpmock.Registry) {},
+		}, {
+			name: "unavailable logs",
+			opts: dispatchOptions{failedLogs: true},
+			run:  failedRun,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/logs"),
+					httpmock.StatusStringResponse(410, "Gone"))
+			},
+			wantErr: "! unable to show the logs of failed steps: failed to get run logs: HTTP 410 (https://api.github.com/repos/OWNER/REPO/actions/runs/123/logs)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			defer reg.Verify(t)
+			tt.httpStubs(reg)
+
+			ios, _, stdout, stderr := iostreams.Test()
+
+			opts := tt.opts
+			opts.repo = &ghRepo{Owner: "OWNER", Name: "REPO"}
+			opts.io = ios
+			opts.httpClient = &http.Client{Transport: reg}
+
+			printFailedStepLogs(&opts, ios.Out, tt.run)
+			assert.Equal(t, tt.wantOut, stdout.String())
+			assert.Equal(t, tt.wantErr, stderr.String())
+		})
+	}
+}
+
+func TestLogFileJobName(t *testing.T) {
+	assert.Equal(t, "build  test", logFileJobName("build / test"))
+	assert.Equal(t, "deploy prod", logFileJobName("deploy: prod"))
+}
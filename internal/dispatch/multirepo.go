@@ -0,0 +1,476 @@
+package dispatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/cli/go-gh/v2/pkg/tableprinter"
+)
+
+// repoDispatchFunc sends a dispatch event to opts.repo and returns the
+// resulting runs.
+type repoDispatchFunc func(client *cliapi.Client, opts *dispatchOptions) ([]*shared.Run, error)
+
+// repoDispatch is one of several dispatches run together, along with its
+// state.
+type repoDispatch struct {
+	// name identifies the dispatch in a plan; it's empty otherwise.
+	name     string
+	repo     *ghRepo
+	dispatch repoDispatchFunc
+	// dependsOn are the dispatches whose runs must succeed before this one
+	// is sent.
+	dependsOn []*repoDispatch
+	// done is closed once the dispatch fails or is skipped, or its runs
+	// complete.
+	done chan struct{}
+
+	started    bool
+	dispatched bool
+	skipped    bool
+	runs       []*shared.Run
+	err        error
+}
+
+func newRepoDispatch(name string, repo *ghRepo, dispatch repoDispatchFunc) *repoDispatch {
+	return &repoDispatch{
+		name:     name,
+		repo:     repo,
+		dispatch: dispatch,
+		done:     make(chan struct{}),
+	}
+}
+
+// label identifies the dispatch in progress output.
+func (d *repoDispatch) label() string {
+	if d.name != "" {
+		return d.name
+	}
+
+	return d.repo.RepoFullName()
+}
+
+// result returns the error of the dispatch, or the conclusion error of its
+// first run that didn't succeed, if any.
+func (d *repoDispatch) result(neutralAsSuccess bool) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	for _, run := range d.runs {
+		if err := checkConclusion(run, neutralAsSuccess); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// multiRepoDispatchRun sends the same dispatch event to each of the repos.
+func multiRepoDispatchRun(opts *dispatchOptions, repos []*ghRepo, dispatch repoDispatchFunc) error {
+	dispatches := make([]*repoDispatch, len(repos))
+	for i, repo := range repos {
+		dispatches[i] = newRepoDispatch("", repo, dispatch)
+	}
+
+	return runRepoDispatches(opts, dispatches)
+}
+
+// runRepoDispatches sends each of the dispatches once those it depends on
+// have succeeded, at most opts.maxConcurrency at once, then watches the
+// resulting runs together, or, per opts, waits for or merely reports them. It
+// prints a summary of each dispatch and returns a dispatchesError if any of
+// them didn't succeed.
+func runRepoDispatches(opts *dispatchOptions, dispatches []*repoDispatch) error {
+	if opts.logs {
+		return errors.New("--logs is not supported with multiple dispatches")
+	}
+
+	if opts.downloadArtifacts != "" {
+		return errors.New("--download-artifacts is not supported with multiple dispatches")
+	}
+
+	if opts.cancelOnInterrupt {
+		return errors.New("--cancel-on-interrupt is not supported with multiple dispatches")
+	}
+
+	if opts.timeout > 0 {
+		return errors.New("--timeout is not supported with multiple dispatches")
+	}
+
+	if opts.retry > 0 {
+		return errors.New("--retry is not supported with multiple dispatches")
+	}
+
+	client := cliapi.NewClientFromHTTP(opts.httpClient)
+	ios := opts.io
+	cs := ios.ColorScheme()
+	tty := ios.IsStdoutTTY()
+	watch := !opts.noWatch && !opts.waitForRunOnly
+
+	interval := opts.refreshInterval()
+
+	out := messageWriter(opts)
+
+	// Ctrl+C stops watching, leaving the runs going. stopped is then closed
+	// to stop polling the runs and sending the remaining dispatches.
+	var stops *watchStops
+	if !opts.noWatch {
+		var stop func()
+		stops, stop = newWatchStops(0)
+		defer stop()
+	}
+	stopped := make(chan struct{})
+
+	var mu sync.Mutex
+	slots := make(chan struct{}, max(opts.maxConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for _, d := range dispatches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(d.done)
+
+			for _, dep := range d.dependsOn {
+				<-dep.done
+			}
+
+			mu.Lock()
+			select {
+			case <-stopped:
+				d.skipped = true
+				d.err = errors.New("skipped because watching was interrupted")
+				mu.Unlock()
+				return
+			default:
+			}
+			for _, dep := range d.dependsOn {
+				if dep.result(opts.neutralAsSuccess) != nil {
+					d.skipped = true
+					d.err = fmt.Errorf("skipped because %s did not succeed", dep.label())
+					break
+				}
+			}
+			if d.skipped && watch && !tty {
+				fmt.Fprintf(out, "- %s: %s\n", d.label(), d.err)
+			}
+			d.started = !d.skipped
+			mu.Unlock()
+
+			if d.skipped {
+				return
+			}
+
+			repoOpts := *opts
+			repoOpts.repo = d.repo
+			repoOpts.prompter = nil
+
+			slots <- struct{}{}
+			runs, err := d.dispatch(client, &repoOpts)
+			<-slots
+
+			mu.Lock()
+			d.dispatched, d.runs, d.err = true, runs, err
+			if watch && !tty {
+				if err != nil {
+					fmt.Fprintf(out, "%s %s: %s\n", cs.FailureIcon(), d.label(), err)
+				}
+				for _, run := range runs {
+					fmt.Fprintf(out, "Watching %s\n", runURL(d.repo, run))
+				}
+			}
+			mu.Unlock()
+
+			if err != nil || opts.noWatch {
+				return
+			}
+
+			pollRepoDispatch(&mu, slots, stopped, client, d, interval, func(run *shared.Run) {
+				if watch && !tty {
+					symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+					fmt.Fprintf(out, "%s Run %s (%d) of %s completed with '%s'\n", symbolColor(symbol), run.WorkflowName(), run.ID, d.label(), run.Conclusion)
+				}
+			})
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	interrupted := false
+	if watch && tty {
+		startAlternateScreenBuffer(ios, stops.interrupts)
+		for finished := false; !finished; {
+			mu.Lock()
+			err := renderRepoDashboard(ios, dispatches, interval)
+			mu.Unlock()
+			if err != nil {
+				ios.StopAlternateScreenBuffer()
+				return err
+			}
+
+			select {
+			case <-done:
+				finished = true
+			case <-stops.interrupts:
+				finished, interrupted = true, true
+			case <-time.After(time.Duration(interval) * time.Second):
+			}
+		}
+		ios.StopAlternateScreenBuffer()
+	} else if stops != nil {
+		select {
+		case <-done:
+		case <-stops.interrupts:
+			interrupted = true
+		}
+	} else {
+		<-done
+	}
+
+	if interrupted {
+		close(stopped)
+		return interruptRepoDispatches(ios, out, &mu, dispatches)
+	}
+
+	if !opts.noWatch {
+		for _, d := range dispatches {
+			repoOpts := *opts
+			repoOpts.repo = d.repo
+			for _, run := range d.runs {
+				printFailedStepLogs(&repoOpts, out, run)
+			}
+		}
+	}
+
+	if opts.exporter != nil {
+		fields := opts.exporter.Fields()
+		results := []*runResult{}
+		for _, d := range dispatches {
+			for _, run := range d.runs {
+				result := &runResult{Run: run}
+				if slices.Contains(fields, "jobs") || slices.Contains(fields, "annotations") {
+					var err error
+					result.Run, result.annotations, err = fetchRun(client, d.repo, run, map[int64][]shared.Annotation{})
+					if err != nil {
+						return err
+					}
+				}
+				results = append(results, result)
+			}
+		}
+
+		if err := opts.exporter.Write(ios, results); err != nil {
+			return err
+		}
+	} else {
+		if watch && tty {
+			fmt.Fprintln(ios.Out)
+		}
+		if err := printRepoDispatches(ios.Out, ios, dispatches); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	var firstErr error
+	for _, d := range dispatches {
+		err := d.err
+		if !opts.noWatch {
+			err = d.result(opts.neutralAsSuccess)
+		}
+
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return &dispatchesError{
+			failed: failed,
+			total:  len(dispatches),
+			err:    firstErr,
+		}
+	}
+
+	return nil
+}
+
+// interruptRepoDispatches prints a summary of the dispatches once watching
+// them is interrupted and returns an interruptedError listing the runs left
+// running.
+func interruptRepoDispatches(ios *iostreams.IOStreams, out io.Writer, mu *sync.Mutex, dispatches []*repoDispatch) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	interrupted := &interruptedError{}
+	for _, d := range dispatches {
+		for _, run := range d.runs {
+			if run.Status != shared.Completed {
+				interrupted.runIDs = append(interrupted.runIDs, run.ID)
+			}
+		}
+	}
+
+	if ios.IsStdoutTTY() {
+		fmt.Fprintln(out)
+	}
+	if err := printRepoDispatches(out, ios, dispatches); err != nil {
+		return err
+	}
+
+	return interrupted
+}
+
+// pollRepoDispatch polls the dispatch's runs every interval seconds until
+// they all complete or stopped is closed, taking one of the slots for each
+// request and calling onCompleted as each run completes.
+func pollRepoDispatch(mu *sync.Mutex, slots chan struct{}, stopped <-chan struct{}, client *cliapi.Client, d *repoDispatch, interval int, onCompleted func(*shared.Run)) {
+	mu.Lock()
+	for _, run := range d.runs {
+		if run.Status == shared.Completed {
+			onCompleted(run)
+		}
+	}
+	mu.Unlock()
+
+	for {
+		pending := false
+		for _, run := range d.runs {
+			if run.Status != shared.Completed {
+				pending = true
+			}
+		}
+		if !pending {
+			return
+		}
+
+		select {
+		case <-stopped:
+			return
+		case <-time.After(time.Duration(interval) * time.Second):
+		}
+
+		for i, run := range d.runs {
+			if run.Status == shared.Completed {
+				continue
+			}
+
+			slots <- struct{}{}
+			updated, err := shared.GetRun(client, d.repo, fmt.Sprintf("%d", run.ID), 0)
+			<-slots
+
+			mu.Lock()
+			if err != nil {
+				d.err = fmt.Errorf("failed to get run: %w", err)
+				mu.Unlock()
+				return
+			}
+
+			d.runs[i] = updated
+			if updated.Status == shared.Completed {
+				onCompleted(updated)
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// renderRepoDashboard redraws the screen with the status of each dispatch.
+func renderRepoDashboard(ios *iostreams.IOStreams, dispatches []*repoDispatch, interval int) error {
+	cs := ios.ColorScheme()
+	out := &bytes.Buffer{}
+
+	fmt.Fprintln(out, cs.Boldf("Refreshing the status of %d dispatches every %d seconds. Press Ctrl+C to quit.", len(dispatches), interval))
+	fmt.Fprintln(out)
+	if err := printRepoDispatches(out, ios, dispatches); err != nil {
+		return err
+	}
+
+	ios.RefreshScreen()
+	_, err := io.Copy(ios.Out, out)
+
+	return err
+}
+
+// printRepoDispatches prints a table row for each run of each dispatch, or
+// for the dispatch itself if it has no runs. The table has a NAME column if
+// any of the dispatches is named.
+func printRepoDispatches(out io.Writer, ios *iostreams.IOStreams, dispatches []*repoDispatch) error {
+	cs := ios.ColorScheme()
+	tp := tableprinter.New(out, ios.IsStdoutTTY(), ios.TerminalWidth())
+
+	named := slices.ContainsFunc(dispatches, func(d *repoDispatch) bool {
+		return d.name != ""
+	})
+
+	header := []string{"REPOSITORY", "WORKFLOW", "RUN", "STATUS", "URL"}
+	if named {
+		header = append([]string{"NAME"}, header...)
+	}
+	tp.AddHeader(header)
+
+	addRow := func(d *repoDispatch, run *shared.Run, status string, statusColor func(string) string) {
+		if named {
+			tp.AddField(d.name, tableprinter.WithColor(cs.Bold))
+		}
+		tp.AddField(d.repo.RepoFullName())
+		if run != nil {
+			tp.AddField(run.WorkflowName())
+			tp.AddField(fmt.Sprintf("%d", run.ID), tableprinter.WithColor(cs.Cyan))
+		} else {
+			tp.AddField("")
+			tp.AddField("")
+		}
+		tp.AddField(status, tableprinter.WithColor(statusColor))
+		if run != nil {
+			tp.AddField(runURL(d.repo, run))
+		} else {
+			tp.AddField("")
+		}
+		tp.EndRow()
+	}
+
+	for _, d := range dispatches {
+		for _, run := range d.runs {
+			symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+			status := string(run.Status)
+			if run.Status == shared.Completed {
+				status = string(run.Conclusion)
+			}
+
+			addRow(d, run, fmt.Sprintf("%s %s", symbol, status), symbolColor)
+		}
+
+		switch {
+		case d.skipped:
+			addRow(d, nil, fmt.Sprintf("- %s", d.err), cs.Muted)
+		case d.err != nil:
+			addRow(d, nil, fmt.Sprintf("X %s", d.err), cs.Red)
+		case len(d.runs) > 0:
+		case d.dispatched:
+			addRow(d, nil, "no runs", cs.Muted)
+		case d.started:
+			addRow(d, nil, "dispatching", cs.Muted)
+		default:
+			addRow(d, nil, "waiting", cs.Muted)
+		}
+	}
+
+	return tp.Render()
+}
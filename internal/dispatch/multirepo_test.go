@@ -0,0 +1,241 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/cmdutil"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiRepoDispatchRun(t *testing.T) {
+	repos := []*ghRepo{
+		{Owner: "OWNER", Name: "ONE"},
+		{Owner: "OWNER", Name: "TWO"},
+		{Owner: "OWNER", Name: "THREE"},
+	}
+
+	registerRun := func(reg *httpmock.Registry, repo string, status, conclusion string) {
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/actions/runs/123", repo)),
+			httpmock.StringResponse(fmt.Sprintf(`{
+				"id": 123,
+				"workflow_id": 456,
+				"event": "workflow_dispatch",
+				"status": "%s",
+				"conclusion": "%s",
+				"jobs_url": "https://api.github.com/repos/OWNER/%s/actions/runs/123/jobs"
+			}`, status, conclusion, repo)))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/actions/workflows/456", repo)),
+			httpmock.StringResponse(getWorkflowResponse))
+	}
+
+	// dispatch fetches the run of the repository, failing for repository TWO.
+	dispatch := func(client *cliapi.Client, opts *dispatchOptions) ([]*shared.Run, error) {
+		if opts.repo.Name == "TWO" {
+			return nil, errors.New("HTTP 404: Not Found")
+		}
+
+		run, err := shared.GetRun(client, opts.repo, "123", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*shared.Run{run}, nil
+	}
+
+	tests := []struct {
+		name         string
+		opts         dispatchOptions
+		httpStubs    func(*httpmock.Registry)
+		wantErr      bool
+		errMsg       string
+		wantExitCode int
+		wantLines    []string
+		wantSummary  string
+	}{
+		{
+			name: "watched runs",
+			opts: dispatchOptions{interval: 1},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRun(reg, "ONE", "completed", "success")
+				registerRun(reg, "THREE", "in_progress", "")
+				registerRun(reg, "THREE", "completed", "failure")
+			},
+			wantLines: []string{
+				"Watching https://github.com/OWNER/ONE/actions/runs/123",
+				"✓ Run foo (123) of OWNER/ONE completed with 'success'",
+				"X OWNER/TWO: HTTP 404: Not Found",
+				"Watching https://github.com/OWNER/THREE/actions/runs/123",
+				"X Run foo (123) of OWNER/THREE completed with 'failure'",
+			},
+			wantSummary: "OWNER/ONE\tfoo\t123\t✓ success\thttps://github.com/OWNER/ONE/actions/runs/123\n" +
+				"OWNER/TWO\t\t\tX HTTP 404: Not Found\t\n" +
+				"OWNER/THREE\tfoo\t123\tX failure\thttps://github.com/OWNER/THREE/actions/runs/123\n",
+			wantErr:      true,
+			errMsg:       "2 of 3 dispatches did not succeed; first error: HTTP 404: Not Found",
+			wantExitCode: exitError,
+		}, {
+			name: "runs that aren't watched",
+			opts: dispatchOptions{noWatch: true},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRun(reg, "ONE", "queued", "")
+				registerRun(reg, "THREE", "queued", "")
+			},
+			wantSummary: "OWNER/ONE\tfoo\t123\t* queued\thttps://github.com/OWNER/ONE/actions/runs/123\n" +
+				"OWNER/TWO\t\t\tX HTTP 404: Not Found\t\n" +
+				"OWNER/THREE\tfoo\t123\t* queued\thttps://github.com/OWNER/THREE/actions/runs/123\n",
+			wantErr:      true,
+			errMsg:       "1 of 3 dispatches did not succeed; first error: HTTP 404: Not Found",
+			wantExitCode: exitError,
+		}, {
+			name: "JSON output",
+			opts: dispatchOptions{
+				exporter: func() cmdutil.Exporter {
+					exporter := cmdutil.NewJSONExporter()
+					exporter.SetFields([]string{"conclusion", "url"})
+					return exporter
+				}(),
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRun(reg, "ONE", "completed", "cancelled")
+				registerRun(reg, "THREE", "completed", "success")
+			},
+			wantSummary:  `[{"conclusion":"cancelled","url":""},{"conclusion":"success","url":""}]` + "\n",
+			wantErr:      true,
+			errMsg:       "2 of 3 dispatches did not succeed; first error: run 123 completed with 'cancelled'",
+			wantExitCode: exitCancelled,
+		}, {
+			name: "failed logs",
+			opts: dispatchOptions{failedLogs: true, failedLogLines: 1},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRun(reg, "ONE", "completed", "success")
+				registerRun(reg, "THREE", "completed", "failure")
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/THREE/actions/runs/123/jobs"),
+					httpmock.StringResponse(`{"jobs": [{
+						"id": 1,
+						"name": "build",
+						"conclusion": "failure",
+						"steps": [{"name": "Test", "number": 1, "conclusion": "failure"}]
+					}]}`))
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/THREE/actions/runs/123/logs"),
+					httpmock.BinaryResponse(createLogsArchive(t, map[string]string{
+						"build/1_Test.txt": "Testing\nFAIL\n",
+					})))
+			},
+			wantLines: []string{
+				"X Run foo (123) of OWNER/THREE completed with 'failure'",
+				"build / Test\n... 1 earlier lines omitted\nFAIL",
+			},
+			wantErr: true,
+			errMsg:  "2 of 3 dispatches did not succeed; first error: HTTP 404: Not Found",
+		}, {
+			name:      "--logs",
+			opts:      dispatchOptions{logs: true},
+			httpStubs: func(reg *httpmock.Registry) {},
+			wantErr:   true,
+			errMsg:    "--logs is not supported with multiple dispatches",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+
+			opts := tt.opts
+			opts.io = ios
+			opts.httpClient = &http.Client{Transport: reg}
+			opts.maxConcurrency = 2
+
+			err := multiRepoDispatchRun(&opts, repos, dispatch)
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+				if tt.wantExitCode != 0 {
+					assert.Equal(t, tt.wantExitCode, ExitCode(err))
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			got := stdout.String()
+			for _, line := range tt.wantLines {
+				assert.Contains(t, got, line+"\n")
+			}
+			assert.True(t, strings.HasSuffix(got, tt.wantSummary), "got stdout:\n%q\nwant suffix:\n%q", got, tt.wantSummary)
+
+			reg.Verify(t)
+		})
+	}
+}
+
+func TestMultiRepoDispatchRunInterrupted(t *testing.T) {
+	// The interrupt is sent once the run has been polled.
+	interrupts := make(chan chan<- os.Signal, 1)
+	defer func(notify func(chan<- os.Signal) func()) { notifyInterrupt = notify }(notifyInterrupt)
+	notifyInterrupt = func(c chan<- os.Signal) func() {
+		interrupts <- c
+		return func() {}
+	}
+
+	run := `{
+		"id": 123,
+		"workflow_id": 456,
+		"event": "workflow_dispatch",
+		"status": "in_progress"
+	}`
+
+	reg := &httpmock.Registry{}
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/ONE/actions/runs/123"),
+		httpmock.StringResponse(run))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/ONE/actions/workflows/456"),
+		httpmock.StringResponse(getWorkflowResponse))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/ONE/actions/runs/123"),
+		httpmock.StringResponse(run))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/ONE/actions/workflows/456"),
+		func(req *http.Request) (*http.Response, error) {
+			(<-interrupts) <- os.Interrupt
+			return httpmock.StringResponse(getWorkflowResponse)(req)
+		})
+
+	dispatch := func(client *cliapi.Client, opts *dispatchOptions) ([]*shared.Run, error) {
+		run, err := shared.GetRun(client, opts.repo, "123", 0)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*shared.Run{run}, nil
+	}
+
+	ios, _, stdout, _ := iostreams.Test()
+	opts := &dispatchOptions{
+		io:             ios,
+		httpClient:     &http.Client{Transport: reg},
+		interval:       1,
+		maxConcurrency: 1,
+	}
+
+	err := multiRepoDispatchRun(opts, []*ghRepo{{Owner: "OWNER", Name: "ONE"}}, dispatch)
+	assert.EqualError(t, err, "interrupted; run 123 left running")
+	assert.Equal(t, exitInterrupted, ExitCode(err))
+	assert.Equal(t, "Watching https://github.com/OWNER/ONE/actions/runs/123\n"+
+		"OWNER/ONE\tfoo\t123\t* in_progress\thttps://github.com/OWNER/ONE/actions/runs/123\n", stdout.String())
+
+	reg.Verify(t)
+}
@@ -0,0 +1,236 @@
+package dispatch
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/iostreams"
+)
+
+// renderResults watches the runs together, or, per opts, waits for or merely
+// reports them. It returns the conclusion error of the first run that didn't
+// succeed, if any.
+func renderResults(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run) error {
+	annotations := make([][]shared.Annotation, len(runs))
+	var err error
+
+	watch := !opts.noWatch && !opts.waitForRunOnly
+	switch {
+	case opts.waitForRunOnly:
+		stops, stop := newWatchStops(opts.timeout)
+		defer stop()
+
+		for i, run := range runs {
+			runs[i], err = waitForRun(client, opts.repo, run, opts.refreshInterval(), stops)
+			if err != nil {
+				// The runs not yet waited for may still be running.
+				incomplete := []*shared.Run{}
+				for _, run := range runs[i:] {
+					if run.Status != shared.Completed {
+						incomplete = append(incomplete, run)
+					}
+				}
+
+				return handleWatchStop(opts, client, incomplete, stops, err)
+			}
+		}
+		// Restore Ctrl+C's default behavior while downloading artifacts.
+		stop()
+	case watch:
+		annotations, err = watchRuns(opts, client, runs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.downloadArtifacts != "" && !opts.noWatch {
+		for _, run := range runs {
+			if err := downloadArtifacts(opts, client, messageWriter(opts), run); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.exporter != nil {
+		fields := opts.exporter.Fields()
+		results := []*runResult{}
+		for i, run := range runs {
+			// Watching the runs already fetched their latest jobs and annotations.
+			if !watch && (slices.Contains(fields, "jobs") || slices.Contains(fields, "annotations")) {
+				run, annotations[i], err = fetchRun(client, opts.repo, run, map[int64][]shared.Annotation{})
+				if err != nil {
+					return err
+				}
+			}
+			results = append(results, &runResult{Run: run, annotations: annotations[i]})
+		}
+
+		if err := opts.exporter.Write(opts.io, results); err != nil {
+			return err
+		}
+	} else if !watch {
+		for i, run := range runs {
+			if i > 0 {
+				fmt.Fprintln(opts.io.Out)
+			}
+			printRunSummary(opts.io.Out, opts.repo, run)
+		}
+	}
+
+	if opts.noWatch {
+		return nil
+	}
+
+	for _, run := range runs {
+		if err := checkConclusion(run, opts.neutralAsSuccess); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchRuns polls the runs every interval seconds until they all complete,
+// updating runs in place and returning their annotations. On a TTY, it redraws
+// a dashboard of every run; otherwise, it logs each run's status transitions.
+// If interrupted or timed out, it stops watching; see handleWatchStop.
+func watchRuns(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run) ([][]shared.Annotation, error) {
+	ios := opts.io
+	cs := ios.ColorScheme()
+	tty := ios.IsStdoutTTY()
+
+	interval := opts.refreshInterval()
+
+	out := messageWriter(opts)
+
+	annotationCaches := make([]map[int64][]shared.Annotation, len(runs))
+	annotations := make([][]shared.Annotation, len(runs))
+	loggers := make([]*runLogger, len(runs))
+	tailers := make([]*logTailer, len(runs))
+	done := make([]bool, len(runs))
+	for i, run := range runs {
+		annotationCaches[i] = map[int64][]shared.Annotation{}
+		loggers[i] = newRunLogger(out, cs)
+		if opts.logs {
+			tailers[i] = newLogTailer(opts.httpClient, opts.repo)
+		}
+		if !tty {
+			fmt.Fprintf(out, "Watching %s\n", runURL(opts.repo, run))
+		}
+	}
+
+	stops, stop := newWatchStops(opts.timeout)
+	defer stop()
+
+	if tty {
+		startAlternateScreenBuffer(ios, stops.interrupts)
+	}
+
+	for {
+		completed := 0
+		for i, run := range runs {
+			if done[i] {
+				completed++
+				continue
+			}
+
+			var err error
+			runs[i], annotations[i], err = fetchRun(client, opts.repo, run, annotationCaches[i])
+			if err != nil {
+				if tty {
+					ios.StopAlternateScreenBuffer()
+				}
+				return nil, err
+			}
+
+			if !tty {
+				loggers[i].log(runs[i])
+				if tailers[i] != nil {
+					if err := tailers[i].tail(out, cs, runs[i].Jobs); err != nil {
+						return nil, err
+					}
+				}
+				if runs[i].Status == shared.Completed {
+					logCompletion(out, cs, runs[i])
+				}
+			}
+
+			if runs[i].Status == shared.Completed {
+				done[i] = true
+				completed++
+			}
+		}
+
+		if tty {
+			if err := renderDashboard(ios, opts.repo, runs, annotations, interval); err != nil {
+				ios.StopAlternateScreenBuffer()
+				return nil, err
+			}
+		}
+
+		if completed == len(runs) {
+			break
+		}
+
+		if err := stops.sleep(time.Duration(interval) * time.Second); err != nil {
+			if tty {
+				ios.StopAlternateScreenBuffer()
+			}
+
+			incomplete := []*shared.Run{}
+			for i, run := range runs {
+				if !done[i] {
+					incomplete = append(incomplete, run)
+				}
+			}
+
+			return nil, handleWatchStop(opts, client, incomplete, stops, err)
+		}
+	}
+
+	if tty {
+		ios.StopAlternateScreenBuffer()
+
+		if opts.exporter == nil {
+			fmt.Fprintln(ios.Out)
+			for _, run := range runs {
+				symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+				fmt.Fprintf(ios.Out, "%s %s (%s) completed with '%s'\n", symbolColor(symbol), cs.Bold(run.WorkflowName()), cs.Cyanf("%d", run.ID), run.Conclusion)
+			}
+		}
+	}
+
+	// Restore Ctrl+C's default behavior for the work that follows, such as
+	// downloading logs and artifacts.
+	stop()
+
+	for _, run := range runs {
+		printFailedStepLogs(opts, out, run)
+	}
+
+	return annotations, nil
+}
+
+// renderDashboard redraws the screen with the status of every run.
+func renderDashboard(ios *iostreams.IOStreams, repo *ghRepo, runs []*shared.Run, annotations [][]shared.Annotation, interval int) error {
+	cs := ios.ColorScheme()
+	out := &bytes.Buffer{}
+
+	fmt.Fprintln(out, cs.Boldf("Refreshing the status of %d runs every %d seconds. Press Ctrl+C to quit.", len(runs), interval))
+	for i, run := range runs {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, cs.Bold(runURL(repo, run)))
+		fmt.Fprintln(out)
+		renderRun(out, cs, run, annotations[i])
+	}
+
+	ios.RefreshScreen()
+	_, err := io.Copy(ios.Out, out)
+
+	return err
+}
@@ -0,0 +1,108 @@
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderResults(t *testing.T) {
+	repo := &ghRepo{Owner: "OWNER", Name: "REPO"}
+
+	registerRun := func(reg *httpmock.Registry, id int64, conclusion, jobsResponse string) {
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/actions/runs/%d", id)),
+			httpmock.StringResponse(fmt.Sprintf(`{
+				"id": %d,
+				"workflow_id": 456,
+				"event": "repository_dispatch",
+				"status": "completed",
+				"conclusion": "%s",
+				"jobs_url": "https://api.github.com/repos/OWNER/REPO/actions/runs/%[1]d/jobs"
+			}`, id, conclusion)))
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows/456"),
+			httpmock.StringResponse(getWorkflowResponse))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/actions/runs/%d/jobs", id)),
+			httpmock.StringResponse(jobsResponse))
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/check-runs/123/annotations"),
+			httpmock.StringResponse("[]"))
+	}
+
+	tests := []struct {
+		name      string
+		opts      dispatchOptions
+		httpStubs func(*httpmock.Registry)
+		wantErr   bool
+		errMsg    string
+		wantOut   string
+	}{
+		{
+			name: "watched runs",
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRun(reg, 123, "success", getJobsResponse)
+				registerRun(reg, 124, "failure", getFailingJobsResponse)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+Watching https://github.com/OWNER/REPO/actions/runs/124
+✓ Job build (ID 123) completed with 'success' in 1m59s
+✓ Run foo (123) completed with 'success'
+✓ Job build (ID 123) completed with 'success' in 1m59s
+  X Test
+X Run foo (124) completed with 'failure'
+`,
+			wantErr: true,
+			errMsg:  "run 124 completed with 'failure'",
+		}, {
+			name: "no-watch runs",
+			opts: dispatchOptions{
+				noWatch: true,
+			},
+			httpStubs: func(reg *httpmock.Registry) {},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
+
+Run ID:      124
+URL:         https://github.com/OWNER/REPO/actions/runs/124
+Workflow ID: 456
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			defer reg.Verify(t)
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+
+			opts := tt.opts
+			opts.repo = repo
+			opts.io = ios
+
+			runs := []*shared.Run{
+				{ID: 123, WorkflowID: 456, Status: shared.Queued},
+				{ID: 124, WorkflowID: 456, Status: shared.Queued},
+			}
+
+			client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+			err := renderResults(&opts, client, runs)
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOut, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package dispatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cli/cli/v2/pkg/iostreams"
+)
+
+// payloadFlags are the flags from which workflow dispatch inputs or a
+// repository dispatch client payload are built.
+type payloadFlags struct {
+	// json is a JSON object, a path to a JSON file prefixed with '@', or
+	// '-' to read the JSON from stdin.
+	json string
+	// rawFields are 'key=value' pairs set as string values.
+	rawFields []string
+	// fields are 'key=value' pairs whose values may reference a file
+	// via '@path', or stdin via '@-'.
+	fields []string
+}
+
+// parse returns the JSON object described by the flags, or nil if none were
+// specified. Fields take precedence over keys in the JSON object. When typed is
+// true, field values of true, false, null, and integers are converted to their
+// JSON types, as with 'gh api --field'; otherwise all values are strings.
+func (f payloadFlags) parse(flagName string, ios *iostreams.IOStreams, typed bool) (any, error) {
+	var payload any
+
+	if f.json != "" {
+		b := []byte(f.json)
+		if f.json == "-" || strings.HasPrefix(f.json, "@") {
+			var err error
+			b, err = ios.ReadUserFile(strings.TrimPrefix(f.json, "@"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read --%s: %w", flagName, err)
+			}
+		}
+
+		var err error
+		payload, err = decodeJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s JSON: %w", flagName, err)
+		}
+	}
+
+	if len(f.rawFields) == 0 && len(f.fields) == 0 {
+		return payload, nil
+	}
+
+	params := map[string]any{}
+	if payload != nil {
+		m, ok := payload.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("--%s must be a JSON object when combined with fields", flagName)
+		}
+		params = m
+	}
+
+	for _, field := range f.rawFields {
+		key, value, err := parseField(field)
+		if err != nil {
+			return nil, err
+		}
+		params[key] = value
+	}
+
+	for _, field := range f.fields {
+		key, value, err := parseField(field)
+		if err != nil {
+			return nil, err
+		}
+
+		params[key], err = magicFieldValue(value, ios, typed)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %q value: %w", key, err)
+		}
+	}
+
+	return params, nil
+}
+
+// decodeJSON strictly decodes a single JSON value, reporting the line and
+// column of any syntax or type error.
+func decodeJSON(b []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&v); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// The offset is that of the byte following the invalid character.
+			line, col := position(b, syntaxErr.Offset-1)
+			return nil, fmt.Errorf("%w at line %d, column %d", err, line, col)
+		}
+		return nil, err
+	}
+
+	if dec.More() {
+		offset := dec.InputOffset()
+		for offset < int64(len(b)) && strings.ContainsRune(" \t\r\n", rune(b[offset])) {
+			offset++
+		}
+		line, col := position(b, offset)
+		return nil, fmt.Errorf("unexpected data after JSON value at line %d, column %d", line, col)
+	}
+
+	return v, nil
+}
+
+// position returns the 1-based line and column of the byte at offset in b.
+func position(b []byte, offset int64) (int, int) {
+	offset = max(0, min(offset, int64(len(b))))
+	before := b[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
+
+func parseField(f string) (string, string, error) {
+	idx := strings.IndexRune(f, '=')
+	if idx == -1 {
+		return f, "", fmt.Errorf("field %q requires a value separated by an '=' sign", f)
+	}
+
+	return f[0:idx], f[idx+1:], nil
+}
+
+func magicFieldValue(v string, ios *iostreams.IOStreams, typed bool) (any, error) {
+	if strings.HasPrefix(v, "@") {
+		b, err := ios.ReadUserFile(v[1:])
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+
+	if !typed {
+		return v, nil
+	}
+
+	if n, err := strconv.Atoi(v); err == nil {
+		return n, nil
+	}
+
+	switch v {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return v, nil
+	}
+}
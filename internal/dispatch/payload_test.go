@@ -0,0 +1,111 @@
+package dispatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadFlagsParse(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "payload.json")
+	assert.NoError(t, os.WriteFile(jsonFile, []byte(`{"name": "Mike"}`), 0600))
+	textFile := filepath.Join(dir, "greeting.txt")
+	assert.NoError(t, os.WriteFile(textFile, []byte("Hello"), 0600))
+
+	tests := []struct {
+		name    string
+		flags   payloadFlags
+		typed   bool
+		stdin   string
+		want    any
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "no flags",
+			want: nil,
+		}, {
+			name:  "JSON string",
+			flags: payloadFlags{json: `{"name": "Mike"}`},
+			want:  map[string]any{"name": "Mike"},
+		}, {
+			name:  "JSON file",
+			flags: payloadFlags{json: "@" + jsonFile},
+			want:  map[string]any{"name": "Mike"},
+		}, {
+			name:  "JSON stdin",
+			flags: payloadFlags{json: "-"},
+			stdin: `{"name": "Mike"}`,
+			want:  map[string]any{"name": "Mike"},
+		}, {
+			name:    "malformed JSON",
+			flags:   payloadFlags{json: "{\n  \"name\": \"Mike\",\n}"},
+			wantErr: true,
+			errMsg:  "invalid --inputs JSON: invalid character '}' looking for beginning of object key string at line 3, column 1",
+		}, {
+			name:    "trailing data",
+			flags:   payloadFlags{json: `{"name": "Mike"} {}`},
+			wantErr: true,
+			errMsg:  "invalid --inputs JSON: unexpected data after JSON value at line 1, column 18",
+		}, {
+			name: "fields override JSON",
+			flags: payloadFlags{
+				json:      `{"name": "Mike", "force_fail": "true"}`,
+				rawFields: []string{"force_fail=false"},
+				fields:    []string{"greeting=@" + textFile, "count=3"},
+			},
+			want: map[string]any{
+				"name":       "Mike",
+				"force_fail": "false",
+				"greeting":   "Hello",
+				"count":      "3",
+			},
+		}, {
+			name:  "typed fields",
+			typed: true,
+			flags: payloadFlags{
+				rawFields: []string{"raw=true"},
+				fields:    []string{"bool=true", "count=3", "nothing=null", "name=Mike"},
+			},
+			want: map[string]any{
+				"raw":     "true",
+				"bool":    true,
+				"count":   3,
+				"nothing": nil,
+				"name":    "Mike",
+			},
+		}, {
+			name:    "field without a value",
+			flags:   payloadFlags{rawFields: []string{"name"}},
+			wantErr: true,
+			errMsg:  `field "name" requires a value separated by an '=' sign`,
+		}, {
+			name: "fields with non-object JSON",
+			flags: payloadFlags{
+				json:      `["foo"]`,
+				rawFields: []string{"name=Mike"},
+			},
+			wantErr: true,
+			errMsg:  "--inputs must be a JSON object when combined with fields",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ios, stdin, _, _ := iostreams.Test()
+			stdin.WriteString(tt.stdin)
+
+			got, err := tt.flags.parse("inputs", ios, tt.typed)
+
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package dispatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	ghapi "github.com/cli/go-gh/v2/pkg/api"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// plan is a set of named dispatches, each sent once the runs of the
+// dispatches it depends on succeed.
+type plan struct {
+	Dispatches []planDispatch `yaml:"dispatches"`
+}
+
+// planDispatch is a named dispatch declared by a plan.
+type planDispatch struct {
+	Name         string `yaml:"name"`
+	dispatchSpec `yaml:",inline"`
+	DependsOn    []string `yaml:"depends_on"`
+}
+
+type planApplyOptions struct {
+	plan *plan
+	dispatchOptions
+}
+
+// NewCmdPlan returns a new plan command.
+func NewCmdPlan() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan <command>",
+		Short: "Send the dispatch events declared by a plan file",
+	}
+
+	cmd.AddCommand(newCmdPlanApply())
+
+	return cmd
+}
+
+func newCmdPlanApply() *cobra.Command {
+	var dOptions dispatchOptions
+
+	cmd := &cobra.Command{
+		Use:   "apply <plan-file>",
+		Short: "Send a plan's dispatch events in the order of their dependencies and watch the resulting GitHub Actions runs",
+		Long: heredoc.Doc(`
+		This command sends each of the dispatch events declared by a YAML plan file, or by
+		stdin if the file is '-', and watches the resulting GitHub Actions runs together.
+
+		Each dispatch is named and declares its repository, its 'type', either 'workflow'
+		or 'repository', and the corresponding options. A dispatch listing other dispatches
+		under 'depends_on' is only sent once all of their runs succeed; otherwise, it's
+		skipped. A dispatch without a 'repo' targets '--repo'.
+
+		The command exits with the code of the first dispatch, in the plan's order, that
+		didn't succeed.
+	`),
+		Example: heredoc.Doc(`
+		# release.yaml
+		dispatches:
+		  - name: build
+		    repo: mdb/service
+		    type: workflow
+		    workflow: build.yaml
+		    ref: main
+		    inputs:
+		      version: 1.2.3
+		  - name: deploy
+		    repo: mdb/infrastructure
+		    type: repository
+		    event_type: deploy
+		    workflow: Deploy
+		    payload:
+		      service: service
+		      version: 1.2.3
+		    depends_on: [build]
+
+		gh dispatch plan apply release.yaml
+	`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ios := iostreams.System()
+			content, err := ios.ReadUserFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read plan: %w", err)
+			}
+
+			for _, name := range []string{"repos-file", "org", "topic"} {
+				if cmd.Flags().Changed(name) {
+					return fmt.Errorf("--%s is not supported by plan apply; specify each dispatch's repo in the plan instead", name)
+				}
+			}
+
+			var defaultRepo string
+			if repos, _ := cmd.Flags().GetStringArray("repo"); len(repos) == 1 {
+				defaultRepo = repos[0]
+			}
+
+			p, err := parsePlan(content, defaultRepo)
+			if err != nil {
+				return err
+			}
+
+			ghClient, err := ghapi.DefaultHTTPClient()
+			if err != nil {
+				return err
+			}
+			dOptions.httpClient = ghClient
+			dOptions.io = ios
+			applyTTYDefaults(cmd, &dOptions)
+
+			return planApplyRun(&planApplyOptions{
+				plan:            p,
+				dispatchOptions: dOptions,
+			})
+		},
+	}
+
+	addDispatchFlags(cmd, &dOptions)
+
+	return cmd
+}
+
+// parsePlan parses and validates a plan, defaulting the repository of its
+// dispatches to defaultRepo.
+func parsePlan(content []byte, defaultRepo string) (*plan, error) {
+	var p plan
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("unable to parse plan YAML: %w", err)
+	}
+
+	if len(p.Dispatches) == 0 {
+		return nil, errors.New("invalid plan: no dispatches")
+	}
+
+	dispatches := map[string]*planDispatch{}
+	for i := range p.Dispatches {
+		d := &p.Dispatches[i]
+		if d.Name == "" {
+			return nil, fmt.Errorf("invalid plan: dispatch %d has no name", i+1)
+		}
+
+		if _, ok := dispatches[d.Name]; ok {
+			return nil, fmt.Errorf("invalid plan: duplicate dispatch %s", d.Name)
+		}
+		dispatches[d.Name] = d
+
+		if d.Repo == "" {
+			d.Repo = defaultRepo
+		}
+
+		if d.Repo == "" {
+			return nil, fmt.Errorf("invalid plan: dispatch %s has no repo", d.Name)
+		}
+
+		if _, err := newGHRepo(d.Repo); err != nil {
+			return nil, fmt.Errorf("invalid plan: dispatch %s: %s: %w", d.Name, d.Repo, err)
+		}
+
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("invalid plan: dispatch %s: %w", d.Name, err)
+		}
+	}
+
+	for _, d := range p.Dispatches {
+		for _, dep := range d.DependsOn {
+			if _, ok := dispatches[dep]; !ok {
+				return nil, fmt.Errorf("invalid plan: dispatch %s depends on unknown dispatch %s", d.Name, dep)
+			}
+		}
+	}
+
+	if cycle := findPlanCycle(p.Dispatches); cycle != nil {
+		return nil, fmt.Errorf("invalid plan: dependency cycle %s", strings.Join(cycle, " -> "))
+	}
+
+	return &p, nil
+}
+
+// findPlanCycle returns the names of the dispatches forming a dependency
+// cycle, starting and ending with the same dispatch, or nil if there's none.
+func findPlanCycle(dispatches []planDispatch) []string {
+	dependsOn := map[string][]string{}
+	for _, d := range dispatches {
+		dependsOn[d.Name] = d.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(path[i:], name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range dependsOn[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, d := range dispatches {
+		if cycle := visit(d.Name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+func planApplyRun(opts *planApplyOptions) error {
+	dispatches := map[string]*repoDispatch{}
+	ordered := []*repoDispatch{}
+	for i := range opts.plan.Dispatches {
+		d := &opts.plan.Dispatches[i]
+
+		repo, err := newGHRepo(d.Repo)
+		if err != nil {
+			return err
+		}
+
+		rd := newRepoDispatch(d.Name, repo, d.dispatchFunc())
+		dispatches[d.Name] = rd
+		ordered = append(ordered, rd)
+	}
+
+	for i, d := range opts.plan.Dispatches {
+		for _, dep := range d.DependsOn {
+			ordered[i].dependsOn = append(ordered[i].dependsOn, dispatches[dep])
+		}
+
+		if opts.noWatch && len(d.DependsOn) > 0 {
+			return errors.New("--no-watch is not supported by plans with dependencies")
+		}
+	}
+
+	return runRepoDispatches(&opts.dispatchOptions, ordered)
+}
@@ -0,0 +1,253 @@
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *plan
+		errMsg  string
+	}{
+		{
+			name: "valid plan",
+			content: heredoc.Doc(`
+				dispatches:
+				  - name: build
+				    type: workflow
+				    workflow: build.yaml
+				    inputs:
+				      force: true
+				      version: 1.2.3
+				  - name: deploy
+				    repo: OWNER/INFRA
+				    type: repository
+				    event_type: deploy
+				    all_workflows: true
+				    payload:
+				      version: 1.2.3
+				    depends_on: [build]
+			`),
+			want: &plan{
+				Dispatches: []planDispatch{{
+					Name: "build",
+					dispatchSpec: dispatchSpec{
+						Repo:     "OWNER/REPO",
+						Type:     "workflow",
+						Workflow: "build.yaml",
+						Inputs:   map[string]string{"force": "true", "version": "1.2.3"},
+					},
+				}, {
+					Name: "deploy",
+					dispatchSpec: dispatchSpec{
+						Repo:         "OWNER/INFRA",
+						Type:         "repository",
+						EventType:    "deploy",
+						AllWorkflows: true,
+						Payload:      map[string]any{"version": "1.2.3"},
+					},
+					DependsOn: []string{"build"},
+				}},
+			},
+		}, {
+			name:    "unknown field",
+			content: "dispatches:\n  - name: build\n    workflows: build.yaml\n",
+			errMsg:  "unable to parse plan YAML: yaml: unmarshal errors:\n  line 3: field workflows not found in type dispatch.planDispatch",
+		}, {
+			name:    "no dispatches",
+			content: "dispatches: []\n",
+			errMsg:  "invalid plan: no dispatches",
+		}, {
+			name:    "unnamed dispatch",
+			content: "dispatches:\n  - type: workflow\n",
+			errMsg:  "invalid plan: dispatch 1 has no name",
+		}, {
+			name:    "duplicate dispatch",
+			content: "dispatches:\n  - {name: build, type: workflow, workflow: build.yaml}\n  - {name: build, type: workflow, workflow: build.yaml}\n",
+			errMsg:  "invalid plan: duplicate dispatch build",
+		}, {
+			name:    "unknown type",
+			content: "dispatches:\n  - {name: build, type: workflow_dispatch}\n",
+			errMsg:  `invalid plan: dispatch build: unknown type "workflow_dispatch"; expected workflow or repository`,
+		}, {
+			name:    "repository dispatch without a workflow",
+			content: "dispatches:\n  - {name: deploy, type: repository, event_type: deploy}\n",
+			errMsg:  "invalid plan: dispatch deploy: a repository dispatch requires a workflow or all_workflows",
+		}, {
+			name:    "workflow dispatch with a payload",
+			content: "dispatches:\n  - {name: build, type: workflow, workflow: build.yaml, payload: {}}\n",
+			errMsg:  "invalid plan: dispatch build: event_type, payload, and all_workflows are only supported by repository dispatches",
+		}, {
+			name:    "unknown dependency",
+			content: "dispatches:\n  - {name: build, type: workflow, workflow: build.yaml, depends_on: [test]}\n",
+			errMsg:  "invalid plan: dispatch build depends on unknown dispatch test",
+		}, {
+			name: "dependency cycle",
+			content: heredoc.Doc(`
+				dispatches:
+				  - {name: build, type: workflow, workflow: build.yaml}
+				  - {name: test, type: workflow, workflow: test.yaml, depends_on: [build, deploy]}
+				  - {name: deploy, type: workflow, workflow: deploy.yaml, depends_on: [test]}
+			`),
+			errMsg: "invalid plan: dependency cycle test -> deploy -> test",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlan([]byte(tt.content), "OWNER/REPO")
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlanApplyRun(t *testing.T) {
+	registerWorkflowDispatch := func(reg *httpmock.Registry, repo string, runID int, conclusion string) {
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s", repo)),
+			httpmock.StringResponse(getRepoResponse))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/actions/workflows/workflow.yaml", repo)),
+			httpmock.StringResponse(getWorkflowResponse))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/contents/.github/workflows/workflow.yaml", repo)),
+			httpmock.StringResponse(getWorkflowContentResponse))
+		reg.Register(
+			httpmock.REST("POST", fmt.Sprintf("repos/OWNER/%s/actions/workflows/456/dispatches", repo)),
+			httpmock.StringResponse(fmt.Sprintf(`{"workflow_run_id": %d}`, runID)))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/actions/runs/%d", repo, runID)),
+			httpmock.StringResponse(fmt.Sprintf(`{
+				"id": %d,
+				"workflow_id": 456,
+				"event": "workflow_dispatch",
+				"status": "completed",
+				"conclusion": "%s"
+			}`, runID, conclusion)))
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/OWNER/%s/actions/workflows/456", repo)),
+			httpmock.StringResponse(getWorkflowResponse))
+	}
+
+	content := heredoc.Doc(`
+		dispatches:
+		  - name: build
+		    repo: OWNER/ONE
+		    type: workflow
+		    workflow: workflow.yaml
+		    inputs: {foo: bar}
+		  - name: test
+		    repo: OWNER/TWO
+		    type: workflow
+		    workflow: workflow.yaml
+		    inputs: {foo: bar}
+		    depends_on: [build]
+		  - name: deploy
+		    repo: OWNER/THREE
+		    type: workflow
+		    workflow: workflow.yaml
+		    inputs: {foo: bar}
+		    depends_on: [test]
+	`)
+
+	tests := []struct {
+		name         string
+		httpStubs    func(*httpmock.Registry)
+		noWatch      bool
+		wantErr      bool
+		errMsg       string
+		wantExitCode int
+		wantOut      string
+	}{
+		{
+			name: "successful prerequisites",
+			httpStubs: func(reg *httpmock.Registry) {
+				registerWorkflowDispatch(reg, "ONE", 1, "success")
+				registerWorkflowDispatch(reg, "TWO", 2, "success")
+				registerWorkflowDispatch(reg, "THREE", 3, "success")
+			},
+			wantOut: heredoc.Doc(`
+				Watching https://github.com/OWNER/ONE/actions/runs/1
+				✓ Run foo (1) of build completed with 'success'
+				Watching https://github.com/OWNER/TWO/actions/runs/2
+				✓ Run foo (2) of test completed with 'success'
+				Watching https://github.com/OWNER/THREE/actions/runs/3
+				✓ Run foo (3) of deploy completed with 'success'
+				build	OWNER/ONE	foo	1	✓ success	https://github.com/OWNER/ONE/actions/runs/1
+				test	OWNER/TWO	foo	2	✓ success	https://github.com/OWNER/TWO/actions/runs/2
+				deploy	OWNER/THREE	foo	3	✓ success	https://github.com/OWNER/THREE/actions/runs/3
+			`),
+		}, {
+			name: "failed prerequisite",
+			httpStubs: func(reg *httpmock.Registry) {
+				registerWorkflowDispatch(reg, "ONE", 1, "success")
+				registerWorkflowDispatch(reg, "TWO", 2, "failure")
+			},
+			wantOut: heredoc.Doc(`
+				Watching https://github.com/OWNER/ONE/actions/runs/1
+				✓ Run foo (1) of build completed with 'success'
+				Watching https://github.com/OWNER/TWO/actions/runs/2
+				X Run foo (2) of test completed with 'failure'
+				- deploy: skipped because test did not succeed
+				build	OWNER/ONE	foo	1	✓ success	https://github.com/OWNER/ONE/actions/runs/1
+				test	OWNER/TWO	foo	2	X failure	https://github.com/OWNER/TWO/actions/runs/2
+			`) + "deploy\tOWNER/THREE\t\t\t- skipped because test did not succeed\t\n",
+			wantErr:      true,
+			errMsg:       "2 of 3 dispatches did not succeed; first error: run 2 completed with 'failure'",
+			wantExitCode: exitFailure,
+		}, {
+			name:      "--no-watch with dependencies",
+			httpStubs: func(reg *httpmock.Registry) {},
+			noWatch:   true,
+			wantErr:   true,
+			errMsg:    "--no-watch is not supported by plans with dependencies",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+
+			p, err := parsePlan([]byte(content), "")
+			assert.NoError(t, err)
+
+			err = planApplyRun(&planApplyOptions{
+				plan: p,
+				dispatchOptions: dispatchOptions{
+					io:             ios,
+					httpClient:     &http.Client{Transport: reg},
+					maxConcurrency: 2,
+					noWatch:        tt.noWatch,
+				},
+			})
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+				if tt.wantExitCode != 0 {
+					assert.Equal(t, tt.wantExitCode, ExitCode(err))
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantOut, stdout.String())
+
+			reg.Verify(t)
+		})
+	}
+}
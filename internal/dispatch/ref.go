@@ -0,0 +1,251 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	cliapi "github.com/cli/cli/v2/api"
+)
+
+// shaPattern matches a full commit SHA.
+var shaPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// gitRef is a git reference, or an annotated tag, as returned by the git
+// database API.
+type gitRef struct {
+	Ref    string `json:"ref"`
+	Object struct {
+		SHA  string `json:"sha"`
+		Type string `json:"type"`
+	} `json:"object"`
+}
+
+// workflowRef is the ref to which a workflow dispatch event is sent, along
+// with the head branch and commit of the resulting run.
+type workflowRef struct {
+	ref string
+	// headBranch is the branch or tag name of ref, which the resulting run
+	// reports as its head_branch. It's empty if ref is neither.
+	headBranch string
+	// sha is the commit ref points to. It's only resolved when required.
+	sha string
+}
+
+// resolveWorkflowRef returns the ref to which opts' workflow dispatch event is
+// sent: the local checkout's current branch if opts.currentBranch is set, the
+// repository's default branch if no ref is specified, a branch at the
+// specified commit SHA, as events can only be sent to branches and tags, or
+// the specified ref once it's verified to exist. The ref's commit is resolved
+// if opts.matchHeadSHA is set.
+func resolveWorkflowRef(client *cliapi.Client, opts *workflowDispatchOptions) (*workflowRef, error) {
+	if opts.currentBranch {
+		return currentBranchRef(client, opts.repo, &gitContext{}, opts.matchHeadSHA)
+	}
+
+	if shaPattern.MatchString(opts.ref) {
+		branch, err := getBranchAtCommit(client, opts.repo, opts.ref)
+		if err != nil {
+			return nil, err
+		}
+
+		wr := &workflowRef{ref: branch, headBranch: branch}
+		if opts.matchHeadSHA {
+			wr.sha = opts.ref
+		}
+
+		return wr, nil
+	}
+
+	ref, name := opts.ref, opts.ref
+	if ref == "" {
+		branch, err := getDefaultBranch(client, opts.repo)
+		if err != nil {
+			return nil, err
+		}
+
+		if !opts.matchHeadSHA {
+			return &workflowRef{ref: branch, headBranch: branch}, nil
+		}
+		ref, name = branch, "refs/heads/"+branch
+	}
+
+	r, err := findRef(client, opts.repo, name)
+	if err != nil {
+		return nil, err
+	}
+
+	wr := &workflowRef{
+		ref:        ref,
+		headBranch: refShortName(r.Ref),
+	}
+
+	if opts.matchHeadSHA {
+		wr.sha, err = getRefCommitSHA(client, opts.repo, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return wr, nil
+}
+
+// getDefaultBranch returns the name of the repository's default branch.
+func getDefaultBranch(client *cliapi.Client, repo *ghRepo) (string, error) {
+	var r struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	err := client.REST(repo.RepoHost(), "GET", fmt.Sprintf("repos/%s", repo.RepoFullName()), nil, &r)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the default branch of %s: %w", repo.RepoFullName(), err)
+	}
+
+	return r.DefaultBranch, nil
+}
+
+// getBranchAtCommit returns the name of a branch whose head is the commit sha.
+func getBranchAtCommit(client *cliapi.Client, repo *ghRepo, sha string) (string, error) {
+	var branches []struct {
+		Name string `json:"name"`
+	}
+
+	err := client.REST(repo.RepoHost(), "GET", fmt.Sprintf("repos/%s/commits/%s/branches-where-head", repo.RepoFullName(), sha), nil, &branches)
+	if isNotFound(err) {
+		return "", fmt.Errorf("commit %s not found in %s", sha, repo.RepoFullName())
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the branches at commit %s: %w", sha, err)
+	}
+
+	if len(branches) == 0 {
+		return "", fmt.Errorf("commit %s isn't the head of any branch in %s; specify a branch or tag name as --ref, as workflow dispatch events can't be sent to a commit", sha, repo.RepoFullName())
+	}
+
+	return branches[0].Name, nil
+}
+
+// findRef returns the repository's ref matching ref, a branch or tag name or
+// a full ref such as refs/heads/main.
+func findRef(client *cliapi.Client, repo *ghRepo, ref string) (*gitRef, error) {
+	names := []string{"heads/" + ref, "tags/" + ref}
+	if strings.HasPrefix(ref, "refs/") {
+		names = []string{strings.TrimPrefix(ref, "refs/")}
+	}
+
+	for _, name := range names {
+		r, err := getRef(client, repo, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if r != nil {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ref %s not found in %s", ref, repo.RepoFullName())
+}
+
+// refShortName returns the branch or tag name of the full ref, or an empty
+// string if it's neither.
+func refShortName(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// getRefCommitSHA returns the SHA of the commit r points to, peeling
+// annotated tags.
+func getRefCommitSHA(client *cliapi.Client, repo *ghRepo, r *gitRef) (string, error) {
+	object := r.Object
+	for object.Type == "tag" {
+		var tag gitRef
+		err := client.REST(repo.RepoHost(), "GET", fmt.Sprintf("repos/%s/git/tags/%s", repo.RepoFullName(), object.SHA), nil, &tag)
+		if err != nil {
+			return "", fmt.Errorf("failed to get tag %s: %w", object.SHA, err)
+		}
+		object = tag.Object
+	}
+
+	return object.SHA, nil
+}
+
+// currentBranchRef returns the branch checked out in git once it's verified
+// that the repository's branch of the same name is at the same commit, which
+// is included if matchHeadSHA is set.
+func currentBranchRef(client *cliapi.Client, repo *ghRepo, git *gitContext, matchHeadSHA bool) (*workflowRef, error) {
+	branch, err := git.Branch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the current branch: %w", err)
+	}
+
+	sha, err := git.SHA()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the current commit: %w", err)
+	}
+
+	r, err := getRef(client, repo, "heads/"+branch)
+	if err != nil {
+		return nil, err
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("branch %s isn't pushed to %s; push it before dispatching", branch, repo.RepoFullName())
+	}
+
+	if r.Object.SHA != sha {
+		return nil, fmt.Errorf("branch %s is at %s locally but at %s in %s; push it before dispatching", branch, sha, r.Object.SHA, repo.RepoFullName())
+	}
+
+	wr := &workflowRef{ref: branch, headBranch: branch}
+	if matchHeadSHA {
+		wr.sha = sha
+	}
+
+	return wr, nil
+}
+
+// getRef returns the repository's ref of name, such as heads/main, or nil if
+// it doesn't exist.
+func getRef(client *cliapi.Client, repo *ghRepo, name string) (*gitRef, error) {
+	var r gitRef
+	err := client.REST(repo.RepoHost(), "GET", fmt.Sprintf("repos/%s/git/ref/%s", repo.RepoFullName(), name), nil, &r)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ref %s: %w", name, err)
+	}
+
+	return &r, nil
+}
+
+// isNotFound returns whether err is an API error reporting that the requested
+// resource doesn't exist.
+func isNotFound(err error) bool {
+	var httpErr cliapi.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+
+	// The commits API responds with 422 Unprocessable Entity to unknown SHAs.
+	return httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusUnprocessableEntity
+}
+
+// isForbiddenOrNotFound returns whether err is an API error reporting that the
+// requested resource is inaccessible to the token or doesn't exist.
+func isForbiddenOrNotFound(err error) bool {
+	var httpErr cliapi.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+
+	return httpErr.StatusCode == http.StatusForbidden || httpErr.StatusCode == http.StatusNotFound
+}
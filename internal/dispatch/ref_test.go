@@ -0,0 +1,212 @@
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveWorkflowRef(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	tagSHA := "89abcdef0123456789abcdef0123456789abcdef"
+	notFound := httpmock.StatusStringResponse(http.StatusNotFound, `{"message": "Not Found"}`)
+
+	tests := []struct {
+		name         string
+		ref          string
+		matchHeadSHA bool
+		httpStubs    func(*httpmock.Registry)
+		want         *workflowRef
+		errMsg       string
+	}{
+		{
+			name: "default branch",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO"),
+					httpmock.StringResponse(`{"default_branch": "trunk"}`))
+			},
+			want: &workflowRef{ref: "trunk", headBranch: "trunk"},
+		}, {
+			name:         "default branch with its commit",
+			matchHeadSHA: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO"),
+					httpmock.StringResponse(`{"default_branch": "trunk"}`))
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/trunk"),
+					httpmock.StringResponse(fmt.Sprintf(`{"ref": "refs/heads/trunk", "object": {"sha": "%s", "type": "commit"}}`, sha)))
+			},
+			want: &workflowRef{ref: "trunk", headBranch: "trunk", sha: sha},
+		}, {
+			name: "branch",
+			ref:  "feature",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/feature"),
+					httpmock.StringResponse(fmt.Sprintf(`{"ref": "refs/heads/feature", "object": {"sha": "%s", "type": "commit"}}`, sha)))
+			},
+			want: &workflowRef{ref: "feature", headBranch: "feature"},
+		}, {
+			name:         "annotated tag with its commit",
+			ref:          "v1.0.0",
+			matchHeadSHA: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/v1.0.0"),
+					notFound)
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/tags/v1.0.0"),
+					httpmock.StringResponse(fmt.Sprintf(`{"ref": "refs/tags/v1.0.0", "object": {"sha": "%s", "type": "tag"}}`, tagSHA)))
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/git/tags/%s", tagSHA)),
+					httpmock.StringResponse(fmt.Sprintf(`{"sha": "%s", "object": {"sha": "%s", "type": "commit"}}`, tagSHA, sha)))
+			},
+			want: &workflowRef{ref: "v1.0.0", headBranch: "v1.0.0", sha: sha},
+		}, {
+			name: "full ref",
+			ref:  "refs/tags/v1.0.0",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/tags/v1.0.0"),
+					httpmock.StringResponse(fmt.Sprintf(`{"ref": "refs/tags/v1.0.0", "object": {"sha": "%s", "type": "commit"}}`, sha)))
+			},
+			want: &workflowRef{ref: "refs/tags/v1.0.0", headBranch: "v1.0.0"},
+		}, {
+			name: "SHA",
+			ref:  sha,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/commits/%s/branches-where-head", sha)),
+					httpmock.StringResponse(fmt.Sprintf(`[{"name": "feature", "commit": {"sha": "%s"}}]`, sha)))
+			},
+			want: &workflowRef{ref: "feature", headBranch: "feature"},
+		}, {
+			name:         "SHA with its commit",
+			ref:          sha,
+			matchHeadSHA: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/commits/%s/branches-where-head", sha)),
+					httpmock.StringResponse(fmt.Sprintf(`[{"name": "feature", "commit": {"sha": "%s"}}]`, sha)))
+			},
+			want: &workflowRef{ref: "feature", headBranch: "feature", sha: sha},
+		}, {
+			name: "SHA not at a branch's head",
+			ref:  sha,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/commits/%s/branches-where-head", sha)),
+					httpmock.StringResponse(`[]`))
+			},
+			errMsg: fmt.Sprintf("commit %s isn't the head of any branch in OWNER/REPO; specify a branch or tag name as --ref, as workflow dispatch events can't be sent to a commit", sha),
+		}, {
+			name: "unknown ref",
+			ref:  "missing",
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/missing"),
+					notFound)
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/tags/missing"),
+					notFound)
+			},
+			errMsg: "ref missing not found in OWNER/REPO",
+		}, {
+			name: "unknown SHA",
+			ref:  sha,
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/OWNER/REPO/commits/%s/branches-where-head", sha)),
+					httpmock.StatusStringResponse(http.StatusUnprocessableEntity, `{"message": "No commit found for SHA"}`))
+			},
+			errMsg: fmt.Sprintf("commit %s not found in OWNER/REPO", sha),
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+			got, err := resolveWorkflowRef(client, &workflowDispatchOptions{
+				ref:          tt.ref,
+				matchHeadSHA: tt.matchHeadSHA,
+				dispatchOptions: dispatchOptions{
+					repo: &ghRepo{Owner: "OWNER", Name: "REPO"},
+				},
+			})
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			reg.Verify(t)
+		})
+	}
+}
+
+func TestCurrentBranchRef(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--initial-branch", "feature")
+	runGit(t, dir, "commit", "--allow-empty", "--message", "initial")
+	sha := runGit(t, dir, "rev-parse", "HEAD")
+
+	tests := []struct {
+		name         string
+		remoteSHA    string
+		matchHeadSHA bool
+		want         *workflowRef
+		errMsg       string
+	}{
+		{
+			name:      "pushed branch",
+			remoteSHA: sha,
+			want:      &workflowRef{ref: "feature", headBranch: "feature"},
+		}, {
+			name:         "pushed branch with --match-head-sha",
+			remoteSHA:    sha,
+			matchHeadSHA: true,
+			want:         &workflowRef{ref: "feature", headBranch: "feature", sha: sha},
+		}, {
+			name:   "unpushed branch",
+			errMsg: "branch feature isn't pushed to OWNER/REPO; push it before dispatching",
+		}, {
+			name:      "diverged branch",
+			remoteSHA: "0123456789abcdef0123456789abcdef01234567",
+			errMsg:    fmt.Sprintf("branch feature is at %s locally but at 0123456789abcdef0123456789abcdef01234567 in OWNER/REPO; push it before dispatching", sha),
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			if tt.remoteSHA == "" {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/feature"),
+					httpmock.StatusStringResponse(http.StatusNotFound, `{"message": "Not Found"}`))
+			} else {
+				reg.Register(
+					httpmock.REST("GET", "repos/OWNER/REPO/git/ref/heads/feature"),
+					httpmock.StringResponse(fmt.Sprintf(`{"ref": "refs/heads/feature", "object": {"sha": "%s"}}`, tt.remoteSHA)))
+			}
+
+			client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+			got, err := currentBranchRef(client, &ghRepo{Owner: "OWNER", Name: "REPO"}, &gitContext{dir: dir}, tt.matchHeadSHA)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			reg.Verify(t)
+		})
+	}
+}
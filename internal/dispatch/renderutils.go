@@ -2,85 +2,362 @@ package dispatch
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"time"
 
 	cliapi "github.com/cli/cli/v2/api"
 	"github.com/cli/cli/v2/pkg/cmd/run/shared"
-	"github.com/cli/cli/v2/pkg/cmdutil"
 	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/google/uuid"
 )
 
-func render(ios *iostreams.IOStreams, client *cliapi.Client, repo *ghRepo, run *shared.Run) error {
+const defaultInterval = 2
+
+// renderResult watches the run, or, per opts, waits for or merely reports it.
+// Per --retry, a failed run is re-run and its new attempt watched in turn.
+func renderResult(opts *dispatchOptions, client *cliapi.Client, run *shared.Run) error {
+	var (
+		annotations []shared.Annotation
+		err         error
+	)
+
+	watch := !opts.noWatch && !opts.waitForRunOnly
+	if !opts.noWatch {
+		stops, stop := newWatchStops(opts.timeout)
+		run, annotations, err = watchAttempts(opts, client, run, stops)
+		// Restore Ctrl+C's default behavior for the work that follows, such
+		// as downloading logs and artifacts.
+		stop()
+		if err != nil {
+			return err
+		}
+
+		if watch {
+			printFailedStepLogs(opts, messageWriter(opts), run)
+		}
+	}
+
+	if opts.downloadArtifacts != "" && !opts.noWatch {
+		if err := downloadArtifacts(opts, client, messageWriter(opts), run); err != nil {
+			return err
+		}
+	}
+
+	if opts.exporter != nil {
+		// Watching the run already fetched its latest jobs and annotations.
+		fields := opts.exporter.Fields()
+		if !watch && (slices.Contains(fields, "jobs") || slices.Contains(fields, "annotations")) {
+			run, annotations, err = fetchRun(client, opts.repo, run, map[int64][]shared.Annotation{})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := opts.exporter.Write(opts.io, &runResult{Run: run, annotations: annotations}); err != nil {
+			return err
+		}
+	} else if !watch {
+		printRunSummary(opts.io.Out, opts.repo, run)
+	}
+
+	if opts.noWatch {
+		return nil
+	}
+
+	return checkConclusion(run, opts.neutralAsSuccess)
+}
+
+// messageWriter returns the writer for status messages, which is stdout
+// unless it's reserved for JSON output, keeping it parseable.
+func messageWriter(opts *dispatchOptions) io.Writer {
+	if opts.exporter != nil {
+		return opts.io.ErrOut
+	}
+
+	return opts.io.Out
+}
+
+// printRunSummary writes the run's ID, URL, and workflow ID to out.
+func printRunSummary(out io.Writer, repo *ghRepo, run *shared.Run) {
+	fmt.Fprintf(out, "Run ID:      %d\n", run.ID)
+	fmt.Fprintf(out, "URL:         %s\n", runURL(repo, run))
+	fmt.Fprintf(out, "Workflow ID: %d\n", run.WorkflowID)
+}
+
+// waitForRun polls the run every interval seconds until it completes or
+// stops say otherwise, in which case it returns errInterrupted or
+// errWatchTimeout. It returns the run's last known state even on error.
+func waitForRun(client *cliapi.Client, repo *ghRepo, run *shared.Run, interval int, stops *watchStops) (*shared.Run, error) {
+	for run.Status != shared.Completed {
+		if err := stops.sleep(time.Duration(interval) * time.Second); err != nil {
+			return run, err
+		}
+
+		latest, err := shared.GetRun(client, repo, fmt.Sprintf("%d", run.ID), 0)
+		if err != nil {
+			return run, fmt.Errorf("failed to get run: %w", err)
+		}
+		run = latest
+	}
+
+	return run, nil
+}
+
+// handleWatchStop handles err, as returned by watching the runs, if it
+// reports that watching was interrupted or timed out.
+func handleWatchStop(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run, stops *watchStops, err error) error {
+	switch {
+	case errors.Is(err, errInterrupted):
+		return handleInterrupt(opts, client, runs, stops)
+	case errors.Is(err, errWatchTimeout):
+		return handleTimeout(opts, client, runs, stops)
+	default:
+		return err
+	}
+}
+
+// runURL returns the run's web URL.
+func runURL(repo *ghRepo, run *shared.Run) string {
+	if run.URL != "" {
+		return run.URL
+	}
+
+	return fmt.Sprintf("https://github.com/%s/actions/runs/%d", repo.RepoFullName(), run.ID)
+}
+
+// render watches the run until it completes, returning its final state and
+// annotations, or until stops say otherwise, returning its last known state
+// with errInterrupted or errWatchTimeout.
+func render(opts *dispatchOptions, client *cliapi.Client, run *shared.Run, stops *watchStops) (*shared.Run, []shared.Annotation, error) {
+	ios := opts.io
+	cs := ios.ColorScheme()
+
+	interval := opts.refreshInterval()
+
+	var (
+		annotations []shared.Annotation
+		err         error
+	)
+	if ios.IsStdoutTTY() {
+		run, annotations, err = watchRun(ios, client, opts.repo, run, interval, stops)
+	} else {
+		var tailer *logTailer
+		if opts.logs {
+			tailer = newLogTailer(opts.httpClient, opts.repo)
+		}
+		run, annotations, err = logRun(messageWriter(opts), cs, client, opts.repo, run, interval, tailer, stops)
+	}
+	if err != nil {
+		return run, nil, err
+	}
+
+	symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+	id := cs.Cyanf("%d", run.ID)
+
+	if ios.IsStdoutTTY() && opts.exporter == nil {
+		fmt.Fprintln(ios.Out)
+		fmt.Fprintf(ios.Out, "%s %s (%s) completed with '%s'\n", symbolColor(symbol), cs.Bold(run.Name), id, run.Conclusion)
+	}
+
+	return run, annotations, nil
+}
+
+// printFailedStepLogs writes the logs of the run's failed steps to out, if it
+// failed and opts call for them: in full with --logs, or their last
+// --failed-log-lines lines with --failed-logs. As the logs merely explain the
+// run's conclusion, failing to get them is reported as a warning.
+func printFailedStepLogs(opts *dispatchOptions, out io.Writer, run *shared.Run) {
+	if !shared.IsFailureState(run.Conclusion) || (!opts.logs && !opts.failedLogs) {
+		return
+	}
+
+	maxLines := opts.failedLogLines
+	if opts.logs {
+		maxLines = 0
+	}
+
+	cs := opts.io.ColorScheme()
+
+	// Runs that weren't rendered, such as those of multiple dispatches,
+	// haven't fetched their jobs.
+	client := cliapi.NewClientFromHTTP(opts.httpClient)
+	if _, err := shared.GetJobs(client, opts.repo, run, 0); err != nil {
+		fmt.Fprintf(opts.io.ErrOut, "%s unable to show the logs of failed steps: failed to get jobs: %s\n", cs.WarningIcon(), err)
+		return
+	}
+
+	logs, err := getFailedStepLogs(opts.httpClient, opts.repo, run)
+	if err != nil {
+		fmt.Fprintf(opts.io.ErrOut, "%s unable to show the logs of failed steps: %s\n", cs.WarningIcon(), err)
+		return
+	}
+
+	printStepLogs(out, cs, logs, maxLines)
+}
+
+// watchRun redraws the run's status every interval seconds until it completes.
+// If stops say otherwise, it restores the screen and returns the run with
+// errInterrupted or errWatchTimeout.
+func watchRun(ios *iostreams.IOStreams, client *cliapi.Client, repo *ghRepo, run *shared.Run, interval int, stops *watchStops) (*shared.Run, []shared.Annotation, error) {
 	cs := ios.ColorScheme()
 	annotationCache := map[int64][]shared.Annotation{}
 	out := &bytes.Buffer{}
-	ios.StartAlternateScreenBuffer()
+	startAlternateScreenBuffer(ios, stops.interrupts)
+	defer ios.StopAlternateScreenBuffer()
 
 	for {
 		// Write to a temporary buffer to reduce total number of fetches
-		var err error
-		run, err = renderRun(out, cs, client, repo, run, annotationCache)
+		var (
+			annotations []shared.Annotation
+			err         error
+		)
+		run, annotations, err = fetchRun(client, repo, run, annotationCache)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		renderRun(out, cs, run, annotations)
 
 		// Refresh the screen buffer and write the temporary buffer to stdout
 		ios.RefreshScreen()
 
-		// TODO: should the refresh interval be configurable?
-		interval := 2
 		fmt.Fprintln(ios.Out, cs.Boldf("Refreshing run status every %d seconds. Press Ctrl+C to quit.", interval))
 		fmt.Fprintln(ios.Out)
-		fmt.Fprintln(ios.Out, cs.Boldf("https://github.com/%s/actions/runs/%d", repo.RepoFullName(), run.ID))
+		fmt.Fprintln(ios.Out, cs.Bold(runURL(repo, run)))
 		fmt.Fprintln(ios.Out)
 
 		_, err = io.Copy(ios.Out, out)
 		out.Reset()
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		if run.Status == shared.Completed {
-			break
+			return run, annotations, nil
+		}
+
+		if err := stops.sleep(time.Duration(interval) * time.Second); err != nil {
+			return run, nil, err
 		}
+	}
+}
+
+// logRun polls the run every interval seconds until it completes. Rather than
+// redrawing the run's status, it writes a line to out for each run and job
+// status transition, which keeps non-interactive output, such as CI logs, readable.
+// If tailer isn't nil, it also writes the log of each job once it completes.
+// If stops say otherwise, it returns the run with errInterrupted or errWatchTimeout.
+func logRun(out io.Writer, cs *iostreams.ColorScheme, client *cliapi.Client, repo *ghRepo, run *shared.Run, interval int, tailer *logTailer, stops *watchStops) (*shared.Run, []shared.Annotation, error) {
+	annotationCache := map[int64][]shared.Annotation{}
+	logger := newRunLogger(out, cs)
+	var annotations []shared.Annotation
+
+	fmt.Fprintf(out, "Watching %s\n", runURL(repo, run))
 
-		duration, err := time.ParseDuration(fmt.Sprintf("%ds", interval))
+	for {
+		var err error
+		run, annotations, err = fetchRun(client, repo, run, annotationCache)
 		if err != nil {
-			return fmt.Errorf("could not parse interval: %w", err)
+			return nil, nil, err
+		}
+
+		logger.log(run)
+		if tailer != nil {
+			if err := tailer.tail(out, cs, run.Jobs); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if run.Status == shared.Completed {
+			break
+		}
+
+		if err := stops.sleep(time.Duration(interval) * time.Second); err != nil {
+			return run, nil, err
 		}
-		time.Sleep(duration)
 	}
 
-	ios.StopAlternateScreenBuffer()
+	if len(annotations) > 0 {
+		fmt.Fprintln(out, cs.Bold("ANNOTATIONS"))
+		fmt.Fprintln(out, shared.RenderAnnotations(cs, annotations))
+	}
 
-	symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
-	id := cs.Cyanf("%d", run.ID)
+	logCompletion(out, cs, run)
 
-	if ios.IsStdoutTTY() {
-		fmt.Fprintln(ios.Out)
-		fmt.Fprintf(ios.Out, "%s %s (%s) completed with '%s'\n", symbolColor(symbol), cs.Bold(run.Name), id, run.Conclusion)
+	return run, annotations, nil
+}
+
+// runLogger writes a line for each status transition of a run and its jobs.
+type runLogger struct {
+	out         io.Writer
+	cs          *iostreams.ColorScheme
+	runStatus   shared.Status
+	jobStatuses map[int64]shared.Status
+}
+
+func newRunLogger(out io.Writer, cs *iostreams.ColorScheme) *runLogger {
+	return &runLogger{
+		out:         out,
+		cs:          cs,
+		jobStatuses: map[int64]shared.Status{},
 	}
+}
+
+// log writes a line for each status transition since run was last logged,
+// except for the run's completion; see logCompletion.
+func (l *runLogger) log(run *shared.Run) {
+	cs := l.cs
 
-	if run.Conclusion != shared.Success {
-		return cmdutil.SilentError
+	if run.Status != l.runStatus && run.Status != shared.Completed {
+		symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+		fmt.Fprintf(l.out, "%s Run %s (%d) %s\n", symbolColor(symbol), run.WorkflowName(), run.ID, run.Status)
 	}
+	l.runStatus = run.Status
 
-	return nil
+	for _, job := range run.Jobs {
+		previous, seen := l.jobStatuses[job.ID]
+		l.jobStatuses[job.ID] = job.Status
+		if seen && previous == job.Status {
+			continue
+		}
+
+		symbol, symbolColor := shared.Symbol(cs, job.Status, job.Conclusion)
+		switch job.Status {
+		case shared.InProgress:
+			fmt.Fprintf(l.out, "%s Job %s (ID %d) started\n", symbolColor(symbol), job.Name, job.ID)
+		case shared.Completed:
+			fmt.Fprintf(l.out, "%s Job %s (ID %d) completed with '%s' in %s\n", symbolColor(symbol), job.Name, job.ID, job.Conclusion, jobDuration(job))
+
+			for _, step := range job.Steps {
+				if shared.IsFailureState(step.Conclusion) {
+					stepSymbol, stepSymbolColor := shared.Symbol(cs, step.Status, step.Conclusion)
+					fmt.Fprintf(l.out, "  %s %s\n", stepSymbolColor(stepSymbol), step.Name)
+				}
+			}
+		}
+	}
 }
 
-// renderRun is largely an emulation of the upstream 'gh run watch' implementation...
-// https://github.com/cli/cli/blob/v2.20.2/pkg/cmd/run/watch/watch.go
-func renderRun(out io.Writer, cs *iostreams.ColorScheme, client *cliapi.Client, repo *ghRepo, run *shared.Run, annotationCache map[int64][]shared.Annotation) (*shared.Run, error) {
+// logCompletion writes a line reporting the completed run's conclusion.
+func logCompletion(out io.Writer, cs *iostreams.ColorScheme, run *shared.Run) {
+	symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+	fmt.Fprintf(out, "%s Run %s (%d) completed with '%s'\n", symbolColor(symbol), run.WorkflowName(), run.ID, run.Conclusion)
+}
+
+// fetchRun fetches the latest state of the run, populating its jobs, along
+// with the annotations of its jobs.
+func fetchRun(client *cliapi.Client, repo *ghRepo, run *shared.Run, annotationCache map[int64][]shared.Annotation) (*shared.Run, []shared.Annotation, error) {
 	run, err := shared.GetRun(client, repo, fmt.Sprintf("%d", run.ID), 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get run: %w", err)
+		return nil, nil, fmt.Errorf("failed to get run: %w", err)
 	}
 
 	jobs, err := shared.GetJobs(client, repo, run, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get jobs: %w", err)
+		return nil, nil, fmt.Errorf("failed to get jobs: %w", err)
 	}
 
 	var annotations []shared.Annotation
@@ -104,49 +381,188 @@ func renderRun(out io.Writer, cs *iostreams.ColorScheme, client *cliapi.Client,
 	}
 
 	if annotationErr != nil {
-		return nil, fmt.Errorf("failed to get annotations: %w", annotationErr)
+		return nil, nil, fmt.Errorf("failed to get annotations: %w", annotationErr)
 	}
 
+	return run, annotations, nil
+}
+
+// renderRun is largely an emulation of the upstream 'gh run watch' implementation...
+// https://github.com/cli/cli/blob/v2.20.2/pkg/cmd/run/watch/watch.go
+func renderRun(out io.Writer, cs *iostreams.ColorScheme, run *shared.Run, annotations []shared.Annotation) {
 	fmt.Fprintln(out, shared.RenderRunHeader(cs, *run, "", "", 0))
 	fmt.Fprintln(out)
 
-	if len(jobs) == 0 {
-		return run, nil
+	if len(run.Jobs) == 0 {
+		return
 	}
 
 	fmt.Fprintln(out, cs.Bold("JOBS"))
-	fmt.Fprintln(out, shared.RenderJobs(cs, jobs, true))
+	fmt.Fprintln(out, shared.RenderJobs(cs, run.Jobs, true))
 
 	if len(annotations) > 0 {
 		fmt.Fprintln(out)
 		fmt.Fprintln(out, cs.Bold("ANNOTATIONS"))
 		fmt.Fprintln(out, shared.RenderAnnotations(cs, annotations))
 	}
+}
 
-	return run, nil
+// runFilter identifies the GitHub Actions run triggered by a dispatch event.
+type runFilter struct {
+	event        string
+	workflowID   int64
+	dispatchedAt time.Time
+	dispatchID   string
+	// headBranch and headSHA, if set, are the head branch and commit of the
+	// run.
+	headBranch string
+	headSHA    string
+}
+
+// matches reports whether run could be the run identified by the filter,
+// regardless of its dispatch ID.
+func (f runFilter) matches(run shared.Run) bool {
+	return run.WorkflowID == f.workflowID &&
+		run.Event == f.event &&
+		!run.CreatedAt.Before(f.dispatchedAt) &&
+		(f.headBranch == "" || run.HeadBranch == f.headBranch) &&
+		(f.headSHA == "" || run.HeadSha == f.headSHA)
+}
+
+const (
+	initialDiscoveryBackoff = 1 * time.Second
+	maxDiscoveryBackoff     = 30 * time.Second
+)
+
+// runNotFoundError is returned when no run matching a dispatch event appears
+// before the discovery timeout elapses.
+type runNotFoundError struct {
+	event      string
+	workflowID int64
+	timeout    time.Duration
+}
+
+func (e *runNotFoundError) Error() string {
+	return fmt.Sprintf("no %s run of workflow %d appeared within %s; verify the workflow is enabled and triggered by the dispatched event and ref", e.event, e.workflowID, e.timeout)
+}
+
+func (e *runNotFoundError) ExitCode() int {
+	return exitRunNotFound
 }
 
-func getRunID(client *cliapi.Client, repo *ghRepo, event string, workflowID int64, dispatchedAt time.Time) (int64, error) {
+// getRunID polls for the run matching filter, backing off exponentially
+// between requests. A zero timeout polls indefinitely.
+func getRunID(client *cliapi.Client, repo *ghRepo, filter runFilter, timeout time.Duration) (int64, error) {
 	actor, err := cliapi.CurrentLoginName(client, repo.RepoHost())
 	if err != nil {
 		return 0, err
 	}
 
+	// Without a dispatch ID, the first matching run is assumed to be the
+	// dispatched run. With one, each candidate must be confirmed.
+	limit := 1
+	if filter.dispatchID != "" {
+		limit = 50
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	backoff := initialDiscoveryBackoff
+
 	for {
 		runs, err := shared.GetRunsWithFilter(client, repo, &shared.FilterOptions{
-			WorkflowID: workflowID,
+			WorkflowID: filter.workflowID,
 			Actor:      actor,
-		}, 1, func(run shared.Run) bool {
-			// TODO: should this try to match on a branch too?
-			// https://github.com/cli/cli/blob/trunk/pkg/cmd/run/shared/shared.go#L281
-			return run.WorkflowID == workflowID && run.Event == event && !run.CreatedAt.Before(dispatchedAt)
-		})
+		}, limit, filter.matches)
 		if err != nil {
 			return 0, err
 		}
 
-		if len(runs) > 0 {
-			return runs[0].ID, nil
+		for _, run := range runs {
+			if filter.dispatchID == "" {
+				return run.ID, nil
+			}
+
+			found, err := runHasDispatchID(client, repo, run, filter.dispatchID)
+			if err != nil {
+				return 0, err
+			}
+
+			if found {
+				return run.ID, nil
+			}
+		}
+
+		wait := backoff
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return 0, &runNotFoundError{
+					event:      filter.event,
+					workflowID: filter.workflowID,
+					timeout:    timeout,
+				}
+			}
+			wait = min(wait, remaining)
+		}
+
+		time.Sleep(wait)
+		backoff = min(backoff*2, maxDiscoveryBackoff)
+	}
+}
+
+// runHasDispatchID reports whether the run's display title, job names, or
+// step names contain the dispatch ID. Workflows opt into correlation by
+// surfacing the dispatch ID input or client payload value in one of them,
+// for example via 'run-name'.
+func runHasDispatchID(client *cliapi.Client, repo *ghRepo, run shared.Run, dispatchID string) (bool, error) {
+	if strings.Contains(run.DisplayTitle, dispatchID) {
+		return true, nil
+	}
+
+	jobs, err := shared.GetJobs(client, repo, &run, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	for _, job := range jobs {
+		if strings.Contains(job.Name, dispatchID) {
+			return true, nil
+		}
+
+		for _, step := range job.Steps {
+			if strings.Contains(step.Name, dispatchID) {
+				return true, nil
+			}
 		}
 	}
+
+	return false, nil
+}
+
+// newDispatchID returns a unique ID used to correlate a dispatch event with
+// the GitHub Actions run it triggers.
+var newDispatchID = uuid.NewString
+
+// injectDispatchID returns a copy of the inputs or client payload with the
+// dispatch ID set under key.
+func injectDispatchID(payload any, key, dispatchID string) (any, error) {
+	if payload == nil {
+		return map[string]any{key: dispatchID}, nil
+	}
+
+	m, ok := payload.(map[string]any)
+	if !ok {
+		return nil, errors.New("a dispatch ID can only be injected into a JSON object")
+	}
+
+	injected := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		injected[k] = v
+	}
+	injected[key] = dispatchID
+
+	return injected, nil
 }
@@ -0,0 +1,67 @@
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFilterMatches(t *testing.T) {
+	dispatchedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	run := shared.Run{
+		WorkflowID: 456,
+		Event:      "workflow_dispatch",
+		CreatedAt:  dispatchedAt.Add(time.Second),
+		HeadBranch: "main",
+		HeadSha:    sha,
+	}
+
+	filter := runFilter{
+		event:        "workflow_dispatch",
+		workflowID:   456,
+		dispatchedAt: dispatchedAt,
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*runFilter)
+		want   bool
+	}{
+		{
+			name:   "no head branch or SHA",
+			modify: func(f *runFilter) {},
+			want:   true,
+		}, {
+			name:   "matching head branch and SHA",
+			modify: func(f *runFilter) { f.headBranch, f.headSHA = "main", sha },
+			want:   true,
+		}, {
+			name:   "other head branch",
+			modify: func(f *runFilter) { f.headBranch = "feature" },
+			want:   false,
+		}, {
+			name:   "other head SHA",
+			modify: func(f *runFilter) { f.headSHA = "89abcdef0123456789abcdef0123456789abcdef" },
+			want:   false,
+		}, {
+			name:   "other event",
+			modify: func(f *runFilter) { f.event = "repository_dispatch" },
+			want:   false,
+		}, {
+			name:   "created before the dispatch",
+			modify: func(f *runFilter) { f.dispatchedAt = dispatchedAt.Add(time.Minute) },
+			want:   false,
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := filter
+			tt.modify(&f)
+			assert.Equal(t, tt.want, f.matches(run))
+		})
+	}
+}
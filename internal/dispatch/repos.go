@@ -0,0 +1,151 @@
+package dispatch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/spf13/cobra"
+)
+
+// maxSearchResults is the number of results beyond which the search API
+// doesn't paginate.
+const maxSearchResults = 1000
+
+// addRepoFlags adds the persistent flags selecting the targeted repositories
+// to cmd, defaulting --repo to defaultRepo unless it's empty.
+func addRepoFlags(cmd *cobra.Command, defaultRepo string) {
+	var defaultRepos []string
+	if defaultRepo != "" {
+		defaultRepos = []string{defaultRepo}
+	}
+
+	cmd.PersistentFlags().StringArrayP("repo", "R", defaultRepos, "The targeted repository's full name; repeat to target several repositories")
+	cmd.PersistentFlags().String("repos-file", "", "Target the repositories listed in a file, one full name per line, or '-' to read them from stdin")
+	cmd.PersistentFlags().String("org", "", "Target the non-archived repositories of an organization that have the --topic")
+	cmd.PersistentFlags().String("topic", "", "The topic of the --org repositories to target")
+	cmd.MarkFlagsRequiredTogether("org", "topic")
+}
+
+// getRepoOptions returns the repositories targeted by --repo, --repos-file,
+// and --org and --topic, in that order and without duplicates. The default
+// --repo, the current repository, is only targeted when no other repositories
+// are specified.
+func getRepoOptions(cmd *cobra.Command, ios *iostreams.IOStreams, httpClient *http.Client) ([]*ghRepo, error) {
+	flags := cmd.Flags()
+	names, _ := flags.GetStringArray("repo")
+	reposFile, _ := flags.GetString("repos-file")
+	org, _ := flags.GetString("org")
+	topic, _ := flags.GetString("topic")
+
+	if !flags.Changed("repo") && (reposFile != "" || org != "") {
+		names = nil
+	}
+
+	if reposFile != "" {
+		fileNames, err := readReposFile(ios, reposFile)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, fileNames...)
+	}
+
+	if org != "" {
+		topicNames, err := searchTopicRepos(cliapi.NewClientFromHTTP(httpClient), org, topic)
+		if err != nil {
+			return nil, err
+		}
+		if len(topicNames) == 0 {
+			return nil, fmt.Errorf("no repositories of %s have the topic %s", org, topic)
+		}
+		names = append(names, topicNames...)
+	}
+
+	repos := []*ghRepo{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+
+		repo, err := newGHRepo(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		key := strings.ToLower(fmt.Sprintf("%s/%s", repo.RepoHost(), repo.RepoFullName()))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		repos = append(repos, repo)
+	}
+
+	if len(repos) == 0 {
+		return nil, errors.New("a --repo must be specified in the [HOST/]OWNER/REPO format")
+	}
+
+	return repos, nil
+}
+
+// readReposFile reads repository full names from the file at path, or from
+// stdin if path is '-', one per line. Blank lines and lines starting with '#'
+// are ignored.
+func readReposFile(ios *iostreams.IOStreams, path string) ([]string, error) {
+	content, err := ios.ReadUserFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repositories file: %w", err)
+	}
+
+	names := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+
+	return names, scanner.Err()
+}
+
+// searchTopicRepos returns the full names of the organization's non-archived
+// repositories with the topic.
+func searchTopicRepos(client *cliapi.Client, org, topic string) ([]string, error) {
+	host, _ := auth.DefaultHost()
+	query := url.QueryEscape(fmt.Sprintf("org:%s topic:%s archived:false", org, topic))
+
+	names := []string{}
+	perPage := 100
+	for page := 1; page*perPage <= maxSearchResults; page++ {
+		var result struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				FullName string `json:"full_name"`
+			} `json:"items"`
+		}
+
+		path := fmt.Sprintf("search/repositories?q=%s&per_page=%d&page=%d", query, perPage, page)
+		if err := client.REST(host, "GET", path, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to search repositories: %w", err)
+		}
+
+		for _, item := range result.Items {
+			names = append(names, item.FullName)
+		}
+
+		if len(result.Items) < perPage || len(names) >= result.TotalCount {
+			break
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,115 @@
+package dispatch
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRepoOptions(t *testing.T) {
+	reposFile := filepath.Join(t.TempDir(), "repos.txt")
+	err := os.WriteFile(reposFile, []byte("# services\nOWNER/ONE\n\n  OWNER/TWO  \nghe.example.com/OWNER/THREE\n"), 0o600)
+	assert.NoError(t, err)
+
+	registerSearch := func(reg *httpmock.Registry, page, response string) {
+		q := url.Values{}
+		q.Set("q", "org:OWNER topic:service archived:false")
+		q.Set("per_page", "100")
+		q.Set("page", page)
+
+		reg.Register(
+			httpmock.QueryMatcher("GET", "search/repositories", q),
+			httpmock.StringResponse(response))
+	}
+
+	tests := []struct {
+		name        string
+		defaultRepo string
+		args        []string
+		stdin       string
+		httpStubs   func(*httpmock.Registry)
+		want        []string
+		errMsg      string
+	}{
+		{
+			name:        "default repository",
+			defaultRepo: "github.com/OWNER/REPO",
+			want:        []string{"github.com/OWNER/REPO"},
+		}, {
+			name:        "repeated --repo",
+			defaultRepo: "github.com/OWNER/REPO",
+			args:        []string{"--repo", "OWNER/ONE", "-R", "ghe.example.com/OWNER/TWO", "--repo", "github.com/owner/one"},
+			want:        []string{"github.com/OWNER/ONE", "ghe.example.com/OWNER/TWO"},
+		}, {
+			name:        "repositories file",
+			defaultRepo: "github.com/OWNER/REPO",
+			args:        []string{"--repos-file", reposFile},
+			want:        []string{"github.com/OWNER/ONE", "github.com/OWNER/TWO", "ghe.example.com/OWNER/THREE"},
+		}, {
+			name:  "repositories file and --repo from stdin",
+			args:  []string{"--repo", "OWNER/REPO", "--repos-file", "-"},
+			stdin: "OWNER/ONE\nOWNER/REPO\n",
+			want:  []string{"github.com/OWNER/REPO", "github.com/OWNER/ONE"},
+		}, {
+			name: "organization repositories with a topic",
+			args: []string{"--org", "OWNER", "--topic", "service"},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerSearch(reg, "1", `{"total_count": 2, "items": [{"full_name": "OWNER/ONE"}, {"full_name": "OWNER/TWO"}]}`)
+			},
+			want: []string{"github.com/OWNER/ONE", "github.com/OWNER/TWO"},
+		}, {
+			name: "no organization repositories with a topic",
+			args: []string{"--org", "OWNER", "--topic", "service"},
+			httpStubs: func(reg *httpmock.Registry) {
+				registerSearch(reg, "1", `{"total_count": 0, "items": []}`)
+			},
+			errMsg: "no repositories of OWNER have the topic service",
+		}, {
+			name:   "no repository",
+			errMsg: "a --repo must be specified in the [HOST/]OWNER/REPO format",
+		}, {
+			name:   "invalid repository",
+			args:   []string{"--repo", "REPO"},
+			errMsg: "REPO: invalid repository name",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GH_HOST", "")
+
+			reg := &httpmock.Registry{}
+			if tt.httpStubs != nil {
+				tt.httpStubs(reg)
+			}
+
+			ios, stdin, _, _ := iostreams.Test()
+			stdin.WriteString(tt.stdin)
+
+			cmd := &cobra.Command{}
+			addRepoFlags(cmd, tt.defaultRepo)
+			assert.NoError(t, cmd.ParseFlags(tt.args))
+
+			repos, err := getRepoOptions(cmd, ios, &http.Client{Transport: reg})
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+
+				got := []string{}
+				for _, repo := range repos {
+					got = append(got, repo.RepoHost()+"/"+repo.RepoFullName())
+				}
+				assert.Equal(t, tt.want, got)
+			}
+
+			reg.Verify(t)
+		})
+	}
+}
@@ -3,27 +3,30 @@ package dispatch
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/MakeNowJust/heredoc"
 	cliapi "github.com/cli/cli/v2/api"
 	runShared "github.com/cli/cli/v2/pkg/cmd/run/shared"
-	"github.com/cli/cli/v2/pkg/cmd/workflow/shared"
 	"github.com/cli/cli/v2/pkg/iostreams"
 	ghapi "github.com/cli/go-gh/v2/pkg/api"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
 	"github.com/spf13/cobra"
 )
 
 type repositoryDispatchRequest struct {
 	EventType     string `json:"event_type"`
-	ClientPayload any    `json:"client_payload"`
+	ClientPayload any    `json:"client_payload,omitempty"`
 }
 
 type repositoryDispatchOptions struct {
 	clientPayload any
 	eventType     string
 	workflow      string
+	allWorkflows  bool
 	dispatchOptions
 }
 
@@ -31,8 +34,10 @@ type repositoryDispatchOptions struct {
 func NewCmdRepository() *cobra.Command {
 	var (
 		repositoryEventType     string
-		repositoryClientPayload string
+		repositoryClientPayload payloadFlags
 		repositoryWorkflow      string
+		repositoryAllWorkflows  bool
+		dOptions                dispatchOptions
 	)
 
 	cmd := &cobra.Command{
@@ -40,18 +45,35 @@ func NewCmdRepository() *cobra.Command {
 		repository \
 			--repo [owner/repo] \
 			--event-type [event-type] \
-			--client-payload [json-string] \
-			--workflow [workflow-name]
+			--client-payload [json-string | @file | -] \
+			--workflow [workflow-file-name.yaml | workflow-id | workflow-name]
 	`),
 		Short: "Send a repository dispatch event and watch the resulting GitHub Actions run",
 		Long: heredoc.Doc(`
 		This command sends a repository dispatch event and attempts to find and watch the
-		resulting GitHub Actions run whose name is specified as '--workflow'.
+		resulting GitHub Actions run whose file name, ID, or name is specified as '--workflow'.
 
 		Note that the command assumes the specified workflow supports a repository_dispatch
-		'on' trigger. Also note that the command is vulnerable to race conditions and may
-		watch an unrelated GitHub Actions workflow run in the event that multiple runs of
-		the specified workflow are running concurrently.
+		'on' trigger. If '--workflow' is omitted and the terminal is interactive, the command
+		prompts for one of the repository's active workflows triggered by the event type.
+
+		To watch the runs of every active workflow whose repository_dispatch trigger accepts
+		the event type, specify '--all-workflows' instead. The command then renders the runs
+		together and exits with the code of the first run that didn't succeed.
+
+		Also note that, by default, the command is vulnerable to race conditions
+		and may watch an unrelated GitHub Actions workflow run in the event that multiple runs
+		of the specified workflow are running concurrently.
+
+		To avoid this, specify '--dispatch-id-key'. The command then injects a generated
+		dispatch ID into the client payload under that key and only watches a run whose
+		display title, job names, or step names contain the ID. The workflow must surface
+		the ID, for example via 'run-name'.
+
+		To send the event to several repositories, repeat '--repo', or specify '--repos-file'
+		or '--org' and '--topic'. The command then renders a combined status table of their
+		runs, prints a summary row for each repository, and exits with the code of the first
+		repository whose dispatch failed or whose run didn't succeed.
 	`),
 		Example: heredoc.Doc(`
 		gh dispatch repository \
@@ -59,32 +81,82 @@ func NewCmdRepository() *cobra.Command {
 			--event-type 'hello' \
 			--client-payload '{"name": "Mike"}' \
 			--workflow Hello
+
+		# Build the client payload from typed fields
+		gh dispatch repository \
+			--repo mdb/gh-dispatch \
+			--event-type 'hello' \
+			--workflow Hello \
+			-f name=Mike \
+			-F force_fail=true
+
+		# Correlate the dispatch with its run via a 'dispatch_id' client payload key
+		gh dispatch repository \
+			--repo mdb/gh-dispatch \
+			--event-type 'hello' \
+			--client-payload '{"name": "Mike"}' \
+			--workflow Hello \
+			--dispatch-id-key dispatch_id
+
+		# Watch the runs of every workflow triggered by the event type
+		gh dispatch repository \
+			--repo mdb/gh-dispatch \
+			--event-type 'hello' \
+			--client-payload '{"name": "Mike"}' \
+			--all-workflows
+
+		# Print the resulting run's ID and URL without watching it
+		gh dispatch repository \
+			--repo mdb/gh-dispatch \
+			--event-type 'hello' \
+			--client-payload '{"name": "Mike"}' \
+			--workflow Hello \
+			--no-watch
 	`),
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := getRepoOption(cmd)
+			ios := iostreams.System()
+			repoClientPayload, err := repositoryClientPayload.parse("client-payload", ios, true)
 			if err != nil {
 				return err
 			}
 
-			b := []byte(repositoryClientPayload)
-			var repoClientPayload any
-			json.Unmarshal(b, &repoClientPayload)
-
-			ios := iostreams.System()
 			ghClient, err := ghapi.DefaultHTTPClient()
 			if err != nil {
 				return err
 			}
-			dOptions := dispatchOptions{
-				repo:       repo,
-				httpClient: ghClient,
-				io:         ios,
+
+			repos, err := getRepoOptions(cmd, ios, ghClient)
+			if err != nil {
+				return err
+			}
+			dOptions.repo = repos[0]
+			dOptions.httpClient = ghClient
+			dOptions.io = ios
+			dOptions.prompter = ghprompter.New(os.Stdin, os.Stdout, os.Stderr)
+			applyTTYDefaults(cmd, &dOptions)
+
+			if len(repos) > 1 {
+				if repositoryWorkflow == "" && !repositoryAllWorkflows {
+					return errors.New("--workflow or --all-workflows required when targeting multiple repositories")
+				}
+
+				return multiRepoDispatchRun(&dOptions, repos, func(client *cliapi.Client, opts *dispatchOptions) ([]*runShared.Run, error) {
+					return dispatchRepository(client, &repositoryDispatchOptions{
+						clientPayload:   repoClientPayload,
+						eventType:       repositoryEventType,
+						workflow:        repositoryWorkflow,
+						allWorkflows:    repositoryAllWorkflows,
+						dispatchOptions: *opts,
+					})
+				})
 			}
 
 			return repositoryDispatchRun(&repositoryDispatchOptions{
 				clientPayload:   repoClientPayload,
 				eventType:       repositoryEventType,
 				workflow:        repositoryWorkflow,
+				allWorkflows:    repositoryAllWorkflows,
 				dispatchOptions: dOptions,
 			})
 		},
@@ -92,10 +164,15 @@ func NewCmdRepository() *cobra.Command {
 
 	cmd.Flags().StringVarP(&repositoryEventType, "event-type", "e", "", "The repository dispatch event type.")
 	cmd.MarkFlagRequired("event-type")
-	cmd.Flags().StringVarP(&repositoryClientPayload, "client-payload", "p", "", "The repository dispatch event client payload JSON string.")
-	cmd.MarkFlagRequired("client-payload")
-	cmd.Flags().StringVarP(&repositoryWorkflow, "workflow", "w", "", "The resulting GitHub Actions workflow name.")
-	cmd.MarkFlagRequired("workflow")
+	cmd.Flags().StringVarP(&repositoryClientPayload.json, "client-payload", "p", "", "The repository dispatch event client payload JSON string, a JSON file path prefixed with '@', or '-' to read from stdin.")
+	cmd.Flags().StringArrayVarP(&repositoryClientPayload.rawFields, "raw-field", "f", nil, "Add a string client payload value in `key=value` format.")
+	cmd.Flags().StringArrayVarP(&repositoryClientPayload.fields, "field", "F", nil, "Add a typed client payload value in `key=value` format, respecting @ syntax (see \"gh help api\").")
+	cmd.Flags().StringVarP(&repositoryWorkflow, "workflow", "w", "", "The resulting GitHub Actions workflow file name, ID, or name; prompted for if omitted.")
+	cmd.Flags().BoolVar(&repositoryAllWorkflows, "all-workflows", false, "Watch the runs of every workflow triggered by the event type.")
+	cmd.MarkFlagsMutuallyExclusive("workflow", "all-workflows")
+	cmd.Flags().StringVar(&dOptions.dispatchIDKey, "dispatch-id-key", "", "The client payload key in which to send a generated dispatch ID used to identify the resulting run.")
+	addDispatchFlags(cmd, &dOptions)
+	cmd.MarkFlagsMutuallyExclusive("retry", "all-workflows")
 
 	return cmd
 }
@@ -103,68 +180,106 @@ func NewCmdRepository() *cobra.Command {
 func repositoryDispatchRun(opts *repositoryDispatchOptions) error {
 	ghClient := cliapi.NewClientFromHTTP(opts.httpClient)
 
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(repositoryDispatchRequest{
-		EventType:     opts.eventType,
-		ClientPayload: opts.clientPayload,
-	})
+	runs, err := dispatchRepository(ghClient, opts)
 	if err != nil {
 		return err
 	}
 
-	var in any
-	dispatchedAt := time.Now()
-	err = ghClient.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/dispatches", opts.repo.RepoFullName()), &buf, &in)
-	if err != nil {
-		return err
+	if !opts.allWorkflows {
+		return renderResult(&opts.dispatchOptions, ghClient, runs[0])
 	}
 
-	wfs, err := getWorkflows(ghClient, opts.repo.RepoHost(), opts.repo.RepoFullName())
+	return renderResults(&opts.dispatchOptions, ghClient, runs)
+}
+
+// dispatchRepository sends the repository dispatch event and returns the
+// resulting run of each watched workflow once it appears.
+func dispatchRepository(client *cliapi.Client, opts *repositoryDispatchOptions) ([]*runShared.Run, error) {
+	clientPayload, err := applyTemplates(&opts.dispatchOptions, opts.clientPayload)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid client payload: %w", err)
 	}
 
-	var workflowID int64
-	for _, wf := range wfs {
-		if wf.Name == opts.workflow {
-			workflowID = wf.ID
-			break
+	var dispatchID string
+	if opts.dispatchIDKey != "" {
+		dispatchID = newDispatchID()
+
+		clientPayload, err = injectDispatchID(clientPayload, opts.dispatchIDKey, dispatchID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client payload: %w", err)
 		}
 	}
 
-	runID, err := getRunID(ghClient, opts.repo, "repository_dispatch", workflowID, dispatchedAt)
-	if err != nil {
-		return err
+	var workflowIDs []int64
+	if opts.allWorkflows {
+		wfs, err := findTriggeredWorkflows(client, opts.io, opts.repo, "", func(triggers *workflowTriggers) bool {
+			return triggers.triggeredByRepositoryDispatch(opts.eventType)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(wfs) == 0 {
+			return nil, fmt.Errorf("no active workflows are triggered by repository_dispatch event type %s", opts.eventType)
+		}
+
+		for _, wf := range wfs {
+			workflowIDs = append(workflowIDs, wf.ID)
+		}
+	} else if opts.workflow == "" {
+		if opts.prompter == nil || !opts.io.CanPrompt() {
+			return nil, errors.New("--workflow required when not running interactively")
+		}
+
+		wf, err := selectWorkflow(opts.prompter, client, opts.io, opts.repo, "", func(triggers *workflowTriggers) bool {
+			return triggers.triggeredByRepositoryDispatch(opts.eventType)
+		})
+		if err != nil {
+			return nil, err
+		}
+		workflowIDs = append(workflowIDs, wf.ID)
+	} else {
+		wf, err := resolveWorkflow(client, opts.repo, opts.workflow)
+		if err != nil {
+			return nil, err
+		}
+		workflowIDs = append(workflowIDs, wf.ID)
 	}
 
-	run, err := runShared.GetRun(ghClient, opts.repo, fmt.Sprintf("%d", runID), 0)
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(repositoryDispatchRequest{
+		EventType:     opts.eventType,
+		ClientPayload: clientPayload,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get run: %w", err)
+		return nil, err
 	}
 
-	return render(opts.io, ghClient, opts.repo, run)
-}
-
-func getWorkflows(client *cliapi.Client, repoHost string, repoFullName string) ([]shared.Workflow, error) {
-	perPage := 100
-	page := 1
-	workflows := []shared.Workflow{}
+	var in any
+	dispatchedAt := time.Now()
+	err = client.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/dispatches", opts.repo.RepoFullName()), &buf, &in)
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		result := shared.WorkflowsPayload{}
-		path := fmt.Sprintf("repos/%s/actions/workflows?per_page=%d&page=%d", repoFullName, perPage, page)
-		err := client.REST(repoHost, "GET", path, nil, &result)
+	runs := []*runShared.Run{}
+	for _, workflowID := range workflowIDs {
+		runID, err := getRunID(client, opts.repo, runFilter{
+			event:        "repository_dispatch",
+			workflowID:   workflowID,
+			dispatchedAt: dispatchedAt,
+			dispatchID:   dispatchID,
+		}, opts.discoveryTimeout)
 		if err != nil {
 			return nil, err
 		}
 
-		workflows = append(workflows, result.Workflows...)
-		if len(result.Workflows) < perPage {
-			break
+		run, err := runShared.GetRun(client, opts.repo, fmt.Sprintf("%d", runID), 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get run: %w", err)
 		}
-
-		page++
+		runs = append(runs, run)
 	}
 
-	return workflows, nil
+	return runs, nil
 }
@@ -1,11 +1,14 @@
 package dispatch
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
 
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmdutil"
 	"github.com/cli/cli/v2/pkg/httpmock"
 	"github.com/cli/cli/v2/pkg/iostreams"
 	"github.com/stretchr/testify/assert"
@@ -23,9 +26,9 @@ func TestRepositoryDispatchRun(t *testing.T) {
 		reg.Register(
 			httpmock.REST("POST", fmt.Sprintf("repos/%s/dispatches", repo)),
 			httpmock.RESTPayload(201, "{}", func(params map[string]any) {
+				// The API rejects a null client payload.
 				assert.Equal(t, map[string]any{
-					"event_type":     "hello",
-					"client_payload": any(nil),
+					"event_type": "hello",
 				}, params)
 			}))
 
@@ -76,6 +79,7 @@ func TestRepositoryDispatchRun(t *testing.T) {
 			httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
 			httpmock.StringResponse(fmt.Sprintf(`{
 				"id": 123,
+				"name": "foo",
 				"workflow_id": 456,
 				"status": "completed",
 				"event": "repository_dispatch",
@@ -96,6 +100,7 @@ func TestRepositoryDispatchRun(t *testing.T) {
 		name      string
 		opts      *repositoryDispatchOptions
 		httpStubs func(*httpmock.Registry)
+		tty       bool
 		wantErr   bool
 		errMsg    string
 		wantOut   string
@@ -109,7 +114,8 @@ func TestRepositoryDispatchRun(t *testing.T) {
 			httpStubs: func(reg *httpmock.Registry) {
 				createMockRegistry(reg, "success", getJobsResponse)
 			},
-			wantOut: `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
+			tty: true,
+			wantOut: "\x1b[0;0H\x1b[J" + `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
 
 https://github.com/OWNER/REPO/actions/runs/123
 
@@ -120,6 +126,8 @@ JOBS
 ✓ build in 1m59s (ID 123)
   ✓ Run actions/checkout@v2
   ✓ Test
+
+✓ foo (123) completed with 'success'
 `,
 		}, {
 			name: "unsuccessful workflow run",
@@ -130,26 +138,119 @@ JOBS
 			httpStubs: func(reg *httpmock.Registry) {
 				createMockRegistry(reg, "failure", getFailingJobsResponse)
 			},
-			wantOut: `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+✓ Job build (ID 123) completed with 'success' in 1m59s
+  X Test
+X Run foo (123) completed with 'failure'
+`,
+			wantErr: true,
+			errMsg:  "run 123 completed with 'failure'",
+		}, {
+			name: "workflow run with JSON output",
+			opts: &repositoryDispatchOptions{
+				eventType: "hello",
+				workflow:  "foo",
+				dispatchOptions: dispatchOptions{
+					exporter: func() cmdutil.Exporter {
+						exporter := cmdutil.NewJSONExporter()
+						exporter.SetFields([]string{"annotations", "conclusion", "databaseId"})
+						return exporter
+					}(),
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				createMockRegistry(reg, "success", getJobsResponse)
+			},
+			wantOut: `{"annotations":[],"conclusion":"success","databaseId":123}
+`,
+		}, {
+			name: "no-watch workflow run",
+			opts: &repositoryDispatchOptions{
+				eventType: "hello",
+				workflow:  "foo",
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/dispatches", repo)),
+					httpmock.StringResponse("{}"))
 
-https://github.com/OWNER/REPO/actions/runs/123
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
 
-X  foo · 123
-Triggered via repository_dispatch 
+				reg.Register(
+					httpmock.GraphQL("query UserCurrent{viewer{login}}"),
+					httpmock.StringResponse(currentUserResponse))
 
-JOBS
-✓ build in 1m59s (ID 123)
-  ✓ Run actions/checkout@v2
-  X Test
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456/runs", repo)),
+					httpmock.StringResponse(fmt.Sprintf(getWorkflowRunsResponse, event, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "repository_dispatch",
+						"status": "queued",
+						"html_url": "https://github.com/OWNER/REPO/actions/runs/123"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
 `,
+		}, {
+			name: "dispatch ID with a non-object client payload",
+			opts: &repositoryDispatchOptions{
+				clientPayload: []any{"foo"},
+				eventType:     "hello",
+				workflow:      "foo",
+				dispatchOptions: dispatchOptions{
+					dispatchIDKey: "dispatch_id",
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {},
+			wantOut:   "",
+			wantErr:   true,
+			errMsg:    "invalid client payload: a dispatch ID can only be injected into a JSON object",
+		}, {
+			name: "workflow not found",
+			opts: &repositoryDispatchOptions{
+				eventType: "hello",
+				workflow:  "bar",
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
+			},
+			wantOut: "",
 			wantErr: true,
-			errMsg:  "SilentError",
+			errMsg: `could not find workflow bar; available workflows:
+  - foo (.)`,
 		}, {
 			name: "malformed JSON response",
 			opts: &repositoryDispatchOptions{
 				eventType: "hello",
+				workflow:  "foo",
 			},
 			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
+
 				reg.Register(
 					httpmock.REST("POST", fmt.Sprintf("repos/%s/dispatches", repo)),
 					httpmock.StringResponse("{"))
@@ -164,7 +265,7 @@ JOBS
 		tt.httpStubs(reg)
 
 		ios, _, stdout, _ := iostreams.Test()
-		ios.SetStdoutTTY(false)
+		ios.SetStdoutTTY(tt.tty)
 		ios.SetAlternateScreenBufferEnabled(false)
 
 		tt.opts.repo = ghRepo
@@ -189,3 +290,78 @@ JOBS
 		})
 	}
 }
+
+func TestDispatchRepositoryAllWorkflows(t *testing.T) {
+	repo := &ghRepo{Owner: "OWNER", Name: "REPO"}
+	workflowsResponse := `{
+		"total_count": 3,
+		"workflows": [{
+			"id": 456,
+			"name": "foo",
+			"path": ".github/workflows/hello.yaml",
+			"state": "active"
+		}, {
+			"id": 457,
+			"name": "Dependabot Updates",
+			"path": "dynamic/dependabot/dependabot-updates",
+			"state": "active"
+		}, {
+			"id": 458,
+			"name": "Broken",
+			"path": ".github/workflows/broken.yaml",
+			"state": "active"
+		}]
+	}`
+
+	reg := &httpmock.Registry{}
+	defer reg.Verify(t)
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows"),
+		httpmock.StringResponse(workflowsResponse))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/hello.yaml"),
+		httpmock.StringResponse(fmt.Sprintf(`{"content": "%s"}`, base64.StdEncoding.EncodeToString([]byte("on:\n  repository_dispatch:\n    types: [hello]")))))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/broken.yaml"),
+		httpmock.StringResponse(fmt.Sprintf(`{"content": "%s"}`, base64.StdEncoding.EncodeToString([]byte("on: [")))))
+	reg.Register(
+		httpmock.REST("POST", "repos/OWNER/REPO/dispatches"),
+		httpmock.StatusStringResponse(http.StatusNoContent, ""))
+	reg.Register(
+		httpmock.GraphQL("query UserCurrent{viewer{login}}"),
+		httpmock.StringResponse(currentUserResponse))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows/456/runs"),
+		httpmock.StringResponse(fmt.Sprintf(getWorkflowRunsResponse, "repository_dispatch", "OWNER/REPO")))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows"),
+		httpmock.StringResponse(workflowsResponse))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123"),
+		httpmock.StringResponse(`{
+			"id": 123,
+			"workflow_id": 456,
+			"event": "repository_dispatch"
+		}`))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows/456"),
+		httpmock.StringResponse(getWorkflowResponse))
+
+	ios, _, _, stderr := iostreams.Test()
+	opts := &repositoryDispatchOptions{
+		eventType:    "hello",
+		allWorkflows: true,
+		dispatchOptions: dispatchOptions{
+			repo: repo,
+			io:   ios,
+		},
+	}
+
+	client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+	runs, err := dispatchRepository(client, opts)
+	assert.NoError(t, err)
+	if assert.Len(t, runs, 1) {
+		assert.Equal(t, int64(123), runs[0].ID)
+	}
+	assert.Contains(t, stderr.String(), "! skipping workflow .github/workflows/broken.yaml:")
+}
@@ -0,0 +1,83 @@
+package dispatch
+
+import (
+	"fmt"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// isRetryable reports whether a run that concluded with c is re-run by
+// --retry.
+func isRetryable(c shared.Conclusion) bool {
+	return c == shared.Failure || c == shared.TimedOut
+}
+
+// watchAttempts watches, or waits for, the run per opts, re-running it up to
+// --retry times while it fails, and returns its final attempt.
+func watchAttempts(opts *dispatchOptions, client *cliapi.Client, run *shared.Run, stops *watchStops) (*shared.Run, []shared.Annotation, error) {
+	for retry := 1; ; retry++ {
+		var (
+			annotations []shared.Annotation
+			err         error
+		)
+		if opts.waitForRunOnly {
+			run, err = waitForRun(client, opts.repo, run, opts.refreshInterval(), stops)
+		} else {
+			run, annotations, err = render(opts, client, run, stops)
+		}
+		if err != nil {
+			return nil, nil, handleWatchStop(opts, client, []*shared.Run{run}, stops, err)
+		}
+
+		if retry > opts.retry || !isRetryable(run.Conclusion) {
+			return run, annotations, nil
+		}
+
+		// The final attempt's logs are printed once watching ends.
+		if !opts.waitForRunOnly {
+			printFailedStepLogs(opts, messageWriter(opts), run)
+		}
+
+		run, err = rerunRun(opts, client, run, retry, stops)
+		if err != nil {
+			return nil, nil, handleWatchStop(opts, client, []*shared.Run{run}, stops, err)
+		}
+	}
+}
+
+// rerunRun re-runs the failed run, or only its failed jobs with
+// --retry-failed-jobs-only, and returns the run once its new attempt starts.
+func rerunRun(opts *dispatchOptions, client *cliapi.Client, run *shared.Run, retry int, stops *watchStops) (*shared.Run, error) {
+	cs := opts.io.ColorScheme()
+	out := messageWriter(opts)
+
+	endpoint, rerunning := "rerun", "it"
+	if opts.retryFailedJobsOnly {
+		endpoint, rerunning = "rerun-failed-jobs", "its failed jobs"
+	}
+
+	symbol, symbolColor := shared.Symbol(cs, run.Status, run.Conclusion)
+	fmt.Fprintf(out, "%s Attempt %d of run %d completed with '%s'; re-running %s (retry %d of %d)\n", symbolColor(symbol), run.Attempt, run.ID, run.Conclusion, rerunning, retry, opts.retry)
+
+	err := client.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/actions/runs/%d/%s", opts.repo.RepoFullName(), run.ID, endpoint), nil, nil)
+	if err != nil {
+		return run, fmt.Errorf("failed to re-run run %d: %w", run.ID, err)
+	}
+
+	for {
+		latest, err := shared.GetRun(client, opts.repo, fmt.Sprintf("%d", run.ID), 0)
+		if err != nil {
+			return run, fmt.Errorf("failed to get run: %w", err)
+		}
+
+		if latest.Attempt > run.Attempt {
+			return latest, nil
+		}
+
+		if err := stops.sleep(time.Duration(opts.refreshInterval()) * time.Second); err != nil {
+			return run, err
+		}
+	}
+}
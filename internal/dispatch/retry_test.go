@@ -0,0 +1,128 @@
+package dispatch
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderResultRetry(t *testing.T) {
+	registerAttempt := func(reg *httpmock.Registry, attempt int, status, conclusion string, watched bool) {
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123"),
+			httpmock.StringResponse(fmt.Sprintf(`{
+				"id": 123,
+				"workflow_id": 456,
+				"event": "workflow_dispatch",
+				"run_attempt": %d,
+				"status": "%s",
+				"conclusion": "%s",
+				"jobs_url": "https://api.github.com/repos/OWNER/REPO/actions/runs/123/jobs"
+			}`, attempt, status, conclusion)))
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows/456"),
+			httpmock.StringResponse(getWorkflowResponse))
+		if watched {
+			reg.Register(
+				httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/jobs"),
+				httpmock.StringResponse(`{"total_count": 0, "jobs": []}`))
+		}
+	}
+
+	registerRerun := func(reg *httpmock.Registry, endpoint string) {
+		reg.Register(
+			httpmock.REST("POST", "repos/OWNER/REPO/actions/runs/123/"+endpoint),
+			httpmock.StatusStringResponse(http.StatusCreated, "{}"))
+	}
+
+	tests := []struct {
+		name                string
+		retry               int
+		retryFailedJobsOnly bool
+		httpStubs           func(*httpmock.Registry)
+		wantOut             string
+		errMsg              string
+		wantExitCode        int
+	}{
+		{
+			name:  "successful retry",
+			retry: 2,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerAttempt(reg, 1, "completed", "failure", true)
+				registerRerun(reg, "rerun")
+				registerAttempt(reg, 2, "queued", "", false)
+				registerAttempt(reg, 2, "completed", "success", true)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+X Run foo (123) completed with 'failure'
+X Attempt 1 of run 123 completed with 'failure'; re-running it (retry 1 of 2)
+Watching https://github.com/OWNER/REPO/actions/runs/123
+✓ Run foo (123) completed with 'success'
+`,
+		}, {
+			name:                "retries exhausted",
+			retry:               1,
+			retryFailedJobsOnly: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerAttempt(reg, 1, "completed", "failure", true)
+				registerRerun(reg, "rerun-failed-jobs")
+				registerAttempt(reg, 2, "queued", "", false)
+				registerAttempt(reg, 2, "completed", "timed_out", true)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+X Run foo (123) completed with 'failure'
+X Attempt 1 of run 123 completed with 'failure'; re-running its failed jobs (retry 1 of 1)
+Watching https://github.com/OWNER/REPO/actions/runs/123
+X Run foo (123) completed with 'timed_out'
+`,
+			errMsg:       "run 123 completed with 'timed_out'",
+			wantExitCode: exitTimedOut,
+		}, {
+			name:  "cancelled runs aren't retried",
+			retry: 1,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerAttempt(reg, 1, "completed", "cancelled", true)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+X Run foo (123) completed with 'cancelled'
+`,
+			errMsg:       "run 123 completed with 'cancelled'",
+			wantExitCode: exitCancelled,
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+
+			opts := &dispatchOptions{
+				repo:                &ghRepo{Owner: "OWNER", Name: "REPO"},
+				httpClient:          &http.Client{Transport: reg},
+				io:                  ios,
+				retry:               tt.retry,
+				retryFailedJobsOnly: tt.retryFailedJobsOnly,
+			}
+
+			client := cliapi.NewClientFromHTTP(opts.httpClient)
+			err := renderResult(opts, client, &shared.Run{ID: 123, WorkflowID: 456})
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				assert.Equal(t, tt.wantExitCode, ExitCode(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantOut, stdout.String())
+
+			reg.Verify(t)
+		})
+	}
+}
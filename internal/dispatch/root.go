@@ -26,8 +26,7 @@ func NewCmdRoot(version string) *cobra.Command {
 		defaultRepo = fmt.Sprintf("%s/%s/%s", currentRepo.Host, currentRepo.Owner, currentRepo.Name)
 	}
 
-	var repo string
-	rootCmd.PersistentFlags().StringVarP(&repo, "repo", "R", defaultRepo, "The targeted repository's full name")
+	addRepoFlags(rootCmd, defaultRepo)
 
 	repositoryCmd := NewCmdRepository()
 	rootCmd.AddCommand(repositoryCmd)
@@ -35,5 +34,11 @@ func NewCmdRoot(version string) *cobra.Command {
 	workflowCmd := NewCmdWorkflow()
 	rootCmd.AddCommand(workflowCmd)
 
+	planCmd := NewCmdPlan()
+	rootCmd.AddCommand(planCmd)
+
+	runCmd := NewCmdRun()
+	rootCmd.AddCommand(runCmd)
+
 	return rootCmd
 }
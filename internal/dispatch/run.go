@@ -0,0 +1,243 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	ghapi "github.com/cli/go-gh/v2/pkg/api"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewCmdRun returns a new run command.
+func NewCmdRun() *cobra.Command {
+	var (
+		sets     []string
+		dOptions dispatchOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run <preset>",
+		Short: "Send the dispatch event declared by a named preset and watch the resulting GitHub Actions run",
+		Long: heredoc.Docf(`
+		This command sends the workflow or repository dispatch event declared by a named preset
+		and watches the resulting GitHub Actions run, as the workflow and repository commands do.
+
+		Presets are declared under 'presets' in the user's config file,
+		~/.config/gh-dispatch/config.yml, and in a %[1]s file in the current
+		directory or its closest parent within the git repository. A repository's preset
+		replaces the user's preset of the same name. Each preset declares its 'repo', its
+		'type', either 'workflow' or 'repository', and the options of that type: 'workflow',
+		'ref', and 'inputs', or 'event_type', 'workflow' or 'all_workflows', and 'payload',
+		along with an optional 'dispatch_id_key'.
+
+		'--set key=value' sets an input, or a top-level client payload key, of the preset.
+		Other flags override the corresponding preset values; '--repo', '--repos-file', and
+		'--org' and '--topic' override the preset's repository.
+	`, repoConfigFileName),
+		Example: heredoc.Doc(`
+		# ~/.config/gh-dispatch/config.yml
+		presets:
+		  deploy-staging:
+		    repo: mdb/service
+		    type: workflow
+		    workflow: deploy.yaml
+		    inputs:
+		      environment: staging
+
+		gh dispatch run deploy-staging
+
+		# Override an input and the ref
+		gh dispatch run deploy-staging --set version=1.2.3 --ref release
+	`),
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			paths, err := configPaths()
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			presets, err := loadPresets(paths)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			return slices.Sorted(maps.Keys(presets)), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := configPaths()
+			if err != nil {
+				return err
+			}
+
+			presets, err := loadPresets(paths)
+			if err != nil {
+				return err
+			}
+
+			ios := iostreams.System()
+			spec, err := resolvePreset(presets, args[0], cmd.Flags(), sets, ios)
+			if err != nil {
+				return err
+			}
+
+			ghClient, err := ghapi.DefaultHTTPClient()
+			if err != nil {
+				return err
+			}
+
+			var repos []*ghRepo
+			if spec.Repo == "" || slices.ContainsFunc([]string{"repo", "repos-file", "org"}, cmd.Flags().Changed) {
+				repos, err = getRepoOptions(cmd, ios, ghClient)
+				if err != nil {
+					return err
+				}
+			} else {
+				repo, err := newGHRepo(spec.Repo)
+				if err != nil {
+					return fmt.Errorf("invalid preset %s: %s: %w", args[0], spec.Repo, err)
+				}
+				repos = []*ghRepo{repo}
+			}
+
+			dOptions.repo = repos[0]
+			dOptions.httpClient = ghClient
+			dOptions.io = ios
+			dOptions.prompter = ghprompter.New(os.Stdin, os.Stdout, os.Stderr)
+			applyTTYDefaults(cmd, &dOptions)
+
+			if len(repos) > 1 {
+				return multiRepoDispatchRun(&dOptions, repos, spec.dispatchFunc())
+			}
+
+			if spec.AllWorkflows && dOptions.retry > 0 {
+				return errors.New("--retry is not supported by presets with all_workflows")
+			}
+
+			return spec.run(&dOptions)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "Set a preset input, or top-level client payload value, in `key=value` format, respecting @ syntax (see \"gh help api\").")
+	cmd.Flags().StringP("workflow", "w", "", "Override the preset's GitHub Actions workflow.")
+	cmd.Flags().Bool("all-workflows", false, "Watch the runs of every workflow triggered by the preset's repository dispatch event type.")
+	cmd.MarkFlagsMutuallyExclusive("workflow", "all-workflows")
+	cmd.Flags().String("ref", "", "Override the preset's workflow ref.")
+	cmd.Flags().StringP("event-type", "e", "", "Override the preset's repository dispatch event type.")
+	cmd.Flags().String("dispatch-id-key", "", "Override the preset's dispatch ID key.")
+	addDispatchFlags(cmd, &dOptions)
+
+	return cmd
+}
+
+// resolvePreset returns the named preset with the values of the changed
+// flags, and the sets, applied.
+func resolvePreset(presets map[string]dispatchSpec, name string, flags *pflag.FlagSet, sets []string, ios *iostreams.IOStreams) (*dispatchSpec, error) {
+	spec, ok := presets[name]
+	if !ok {
+		if len(presets) == 0 {
+			return nil, fmt.Errorf("unknown preset %s; no presets are declared", name)
+		}
+
+		return nil, fmt.Errorf("unknown preset %s; available presets: %s", name, strings.Join(slices.Sorted(maps.Keys(presets)), ", "))
+	}
+
+	for flag, value := range map[string]*string{
+		"workflow":        &spec.Workflow,
+		"ref":             &spec.Ref,
+		"event-type":      &spec.EventType,
+		"dispatch-id-key": &spec.DispatchIDKey,
+	} {
+		if flags.Changed(flag) {
+			*value, _ = flags.GetString(flag)
+		}
+	}
+
+	if flags.Changed("workflow") {
+		spec.AllWorkflows = false
+	}
+
+	if flags.Changed("all-workflows") {
+		spec.AllWorkflows, _ = flags.GetBool("all-workflows")
+		if spec.AllWorkflows {
+			spec.Workflow = ""
+		}
+	}
+
+	if err := applySets(&spec, sets, ios); err != nil {
+		return nil, err
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, fmt.Errorf("invalid preset %s: %w", name, err)
+	}
+
+	return &spec, nil
+}
+
+// applySets sets the spec's inputs, or the top-level keys of its client
+// payload, from 'key=value' pairs. As with '--field', client payload values
+// of true, false, null, and integers are set as their JSON types.
+func applySets(spec *dispatchSpec, sets []string, ios *iostreams.IOStreams) error {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	if spec.Type == repositoryDispatchType {
+		payload := map[string]any{}
+		if spec.Payload != nil {
+			m, ok := spec.Payload.(map[string]any)
+			if !ok {
+				return errors.New("--set requires the preset's payload to be an object")
+			}
+			maps.Copy(payload, m)
+		}
+
+		for _, set := range sets {
+			key, value, err := parseField(set)
+			if err != nil {
+				return err
+			}
+
+			payload[key], err = magicFieldValue(value, ios, true)
+			if err != nil {
+				return fmt.Errorf("error parsing %q value: %w", key, err)
+			}
+		}
+		spec.Payload = payload
+
+		return nil
+	}
+
+	inputs := maps.Clone(spec.Inputs)
+	if inputs == nil {
+		inputs = map[string]string{}
+	}
+
+	for _, set := range sets {
+		key, value, err := parseField(set)
+		if err != nil {
+			return err
+		}
+
+		v, err := magicFieldValue(value, ios, false)
+		if err != nil {
+			return fmt.Errorf("error parsing %q value: %w", key, err)
+		}
+		inputs[key] = v.(string)
+	}
+	spec.Inputs = inputs
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package dispatch
+
+import (
+	"testing"
+
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePreset(t *testing.T) {
+	presets := map[string]dispatchSpec{
+		"deploy": {
+			Repo:     "OWNER/REPO",
+			Type:     "workflow",
+			Workflow: "deploy.yaml",
+			Inputs:   map[string]string{"environment": "staging"},
+		},
+		"hello": {
+			Repo:      "OWNER/REPO",
+			Type:      "repository",
+			EventType: "hello",
+			Workflow:  "Hello",
+			Payload:   map[string]any{"name": "Mike"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		preset string
+		args   []string
+		want   *dispatchSpec
+		errMsg string
+	}{
+		{
+			name:   "preset",
+			preset: "deploy",
+			want: &dispatchSpec{
+				Repo:     "OWNER/REPO",
+				Type:     "workflow",
+				Workflow: "deploy.yaml",
+				Inputs:   map[string]string{"environment": "staging"},
+			},
+		}, {
+			name:   "overridden workflow preset",
+			preset: "deploy",
+			args:   []string{"--ref", "release", "--set", "environment=production", "--set", "version=1.2.3"},
+			want: &dispatchSpec{
+				Repo:     "OWNER/REPO",
+				Type:     "workflow",
+				Workflow: "deploy.yaml",
+				Ref:      "release",
+				Inputs:   map[string]string{"environment": "production", "version": "1.2.3"},
+			},
+		}, {
+			name:   "overridden repository preset",
+			preset: "hello",
+			args:   []string{"--all-workflows", "-e", "goodbye", "--set", "force_fail=true"},
+			want: &dispatchSpec{
+				Repo:         "OWNER/REPO",
+				Type:         "repository",
+				EventType:    "goodbye",
+				AllWorkflows: true,
+				Payload:      map[string]any{"name": "Mike", "force_fail": true},
+			},
+		}, {
+			name:   "invalid override",
+			preset: "hello",
+			args:   []string{"--ref", "release"},
+			errMsg: "invalid preset hello: ref and inputs are only supported by workflow dispatches",
+		}, {
+			name:   "unknown preset",
+			preset: "release",
+			errMsg: "unknown preset release; available presets: deploy, hello",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ios, _, _, _ := iostreams.Test()
+
+			cmd := NewCmdRun()
+			assert.NoError(t, cmd.ParseFlags(tt.args))
+			sets, _ := cmd.Flags().GetStringArray("set")
+
+			got, err := resolvePreset(presets, tt.preset, cmd.Flags(), sets, ios)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Sets don't modify the declared presets.
+	assert.Equal(t, map[string]string{"environment": "staging"}, presets["deploy"].Inputs)
+	assert.Equal(t, map[string]any{"name": "Mike"}, presets["hello"].Payload)
+}
@@ -0,0 +1,121 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+
+	cliapi "github.com/cli/cli/v2/api"
+	runShared "github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// The types of dispatch specs.
+const (
+	workflowDispatchType   = "workflow"
+	repositoryDispatchType = "repository"
+)
+
+// dispatchSpec declares a dispatch event, as in a plan or a preset.
+type dispatchSpec struct {
+	Repo string `yaml:"repo"`
+	// Type is either workflowDispatchType or repositoryDispatchType.
+	Type          string            `yaml:"type"`
+	Workflow      string            `yaml:"workflow"`
+	Ref           string            `yaml:"ref"`
+	Inputs        map[string]string `yaml:"inputs"`
+	EventType     string            `yaml:"event_type"`
+	Payload       any               `yaml:"payload"`
+	AllWorkflows  bool              `yaml:"all_workflows"`
+	DispatchIDKey string            `yaml:"dispatch_id_key"`
+}
+
+// validate reports the first missing or conflicting option of the spec's
+// type.
+func (s *dispatchSpec) validate() error {
+	switch s.Type {
+	case workflowDispatchType:
+		if s.Workflow == "" {
+			return errors.New("a workflow dispatch requires a workflow")
+		}
+		if s.EventType != "" || s.Payload != nil || s.AllWorkflows {
+			return errors.New("event_type, payload, and all_workflows are only supported by repository dispatches")
+		}
+	case repositoryDispatchType:
+		if s.EventType == "" {
+			return errors.New("a repository dispatch requires an event_type")
+		}
+		if s.Workflow == "" && !s.AllWorkflows {
+			return errors.New("a repository dispatch requires a workflow or all_workflows")
+		}
+		if s.Workflow != "" && s.AllWorkflows {
+			return errors.New("workflow and all_workflows are mutually exclusive")
+		}
+		if s.Ref != "" || s.Inputs != nil {
+			return errors.New("ref and inputs are only supported by workflow dispatches")
+		}
+	default:
+		return fmt.Errorf("unknown type %q; expected %s or %s", s.Type, workflowDispatchType, repositoryDispatchType)
+	}
+
+	return nil
+}
+
+// workflowOptions returns the options of the spec's workflow dispatch.
+func (s *dispatchSpec) workflowOptions(opts dispatchOptions) *workflowDispatchOptions {
+	opts.dispatchIDKey = s.DispatchIDKey
+
+	var inputs any
+	if len(s.Inputs) > 0 {
+		m := map[string]any{}
+		for k, v := range s.Inputs {
+			m[k] = v
+		}
+		inputs = m
+	}
+
+	return &workflowDispatchOptions{
+		inputs:          inputs,
+		ref:             s.Ref,
+		workflow:        s.Workflow,
+		dispatchOptions: opts,
+	}
+}
+
+// repositoryOptions returns the options of the spec's repository dispatch.
+func (s *dispatchSpec) repositoryOptions(opts dispatchOptions) *repositoryDispatchOptions {
+	opts.dispatchIDKey = s.DispatchIDKey
+
+	return &repositoryDispatchOptions{
+		clientPayload:   s.Payload,
+		eventType:       s.EventType,
+		workflow:        s.Workflow,
+		allWorkflows:    s.AllWorkflows,
+		dispatchOptions: opts,
+	}
+}
+
+// run sends the spec's dispatch event to opts.repo and renders the result.
+func (s *dispatchSpec) run(opts *dispatchOptions) error {
+	if s.Type == repositoryDispatchType {
+		return repositoryDispatchRun(s.repositoryOptions(*opts))
+	}
+
+	return workflowDispatchRun(s.workflowOptions(*opts))
+}
+
+// dispatchFunc returns the function sending the spec's dispatch event.
+func (s *dispatchSpec) dispatchFunc() repoDispatchFunc {
+	if s.Type == repositoryDispatchType {
+		return func(client *cliapi.Client, opts *dispatchOptions) ([]*runShared.Run, error) {
+			return dispatchRepository(client, s.repositoryOptions(*opts))
+		}
+	}
+
+	return func(client *cliapi.Client, opts *dispatchOptions) ([]*runShared.Run, error) {
+		run, err := dispatchWorkflow(client, s.workflowOptions(*opts))
+		if err != nil {
+			return nil, err
+		}
+
+		return []*runShared.Run{run}, nil
+	}
+}
@@ -0,0 +1,161 @@
+package dispatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// templateData is the data available to input and client payload templates.
+type templateData struct {
+	// Vars are the values of --var.
+	Vars map[string]string
+	// Env are the environment variables.
+	Env map[string]string
+	// Git is the local git checkout.
+	Git *gitContext
+}
+
+// gitContext exposes the git checkout in dir to templates. Its methods only
+// run git when a template calls them.
+type gitContext struct {
+	dir string
+}
+
+// Branch returns the name of the checked out branch.
+func (g *gitContext) Branch() (string, error) {
+	return g.run("symbolic-ref", "--short", "HEAD")
+}
+
+// SHA returns the SHA of the HEAD commit.
+func (g *gitContext) SHA() (string, error) {
+	return g.run("rev-parse", "HEAD")
+}
+
+// Tag returns the most recent tag reachable from HEAD.
+func (g *gitContext) Tag() (string, error) {
+	return g.run("describe", "--tags", "--abbrev=0")
+}
+
+// RemoteURL returns the URL of the origin remote.
+func (g *gitContext) RemoteURL() (string, error) {
+	return g.run("remote", "get-url", "origin")
+}
+
+func (g *gitContext) run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// newTemplateData returns the template data of the 'key=value' vars, the
+// environment, and the git checkout in the current directory.
+func newTemplateData(vars []string) (*templateData, error) {
+	data := &templateData{
+		Vars: map[string]string{},
+		Env:  map[string]string{},
+		Git:  &gitContext{},
+	}
+
+	for _, v := range vars {
+		key, value, err := parseField(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --var: %w", err)
+		}
+		data.Vars[key] = value
+	}
+
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			data.Env[key] = value
+		}
+	}
+
+	return data, nil
+}
+
+// applyTemplates renders the templates of the inputs or client payload if
+// --templates or --var is specified, and returns it as is otherwise.
+func applyTemplates(opts *dispatchOptions, payload any) (any, error) {
+	if !opts.templates && len(opts.vars) == 0 {
+		return payload, nil
+	}
+
+	data, err := newTemplateData(opts.vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderTemplates(payload, data)
+}
+
+// renderTemplates returns a copy of the decoded JSON value v in which each
+// string is rendered as a Go template with data. Templates referencing
+// missing vars or environment variables fail.
+func renderTemplates(v any, data *templateData) (any, error) {
+	return renderTemplate(v, data, "")
+}
+
+// renderTemplate renders v, located at path in the rendered value.
+func renderTemplate(v any, data *templateData, path string) (any, error) {
+	switch v := v.(type) {
+	case string:
+		tmpl, err := template.New(path).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+
+		return buf.String(), nil
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for key, value := range v {
+			var err error
+			rendered[key], err = renderTemplate(value, data, joinTemplatePath(path, key))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return rendered, nil
+	case []any:
+		rendered := make([]any, len(v))
+		for i, value := range v {
+			var err error
+			rendered[i], err = renderTemplate(value, data, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return rendered, nil
+	default:
+		return v, nil
+	}
+}
+
+func joinTemplatePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,134 @@
+package dispatch
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    dispatchOptions
+		payload any
+		want    any
+		errMsg  string
+	}{
+		{
+			name:    "templates disabled",
+			payload: map[string]any{"sha": "${{ github.sha }}"},
+			want:    map[string]any{"sha": "${{ github.sha }}"},
+		}, {
+			name: "vars imply templates",
+			opts: dispatchOptions{
+				vars: []string{"version=1.2.3"},
+			},
+			payload: map[string]any{"version": "{{ .Vars.version }}"},
+			want:    map[string]any{"version": "1.2.3"},
+		}, {
+			name: "missing var",
+			opts: dispatchOptions{
+				templates: true,
+			},
+			payload: map[string]any{"version": "{{ .Vars.version }}"},
+			errMsg:  `template: version:1:8: executing "version" at <.Vars.version>: map has no entry for key "version"`,
+		}, {
+			name: "invalid var",
+			opts: dispatchOptions{
+				vars: []string{"version"},
+			},
+			payload: map[string]any{"version": "{{ .Vars.version }}"},
+			errMsg:  `invalid --var: field "version" requires a value separated by an '=' sign`,
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyTemplates(&tt.opts, tt.payload)
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	t.Setenv("GH_DISPATCH_TEST_USER", "mdb")
+
+	data, err := newTemplateData([]string{"name=Mike", "version=1.2.3"})
+	assert.NoError(t, err)
+
+	payload := map[string]any{
+		"name": "{{ .Vars.name }}",
+		"user": "{{ .Env.GH_DISPATCH_TEST_USER }}",
+		"nested": map[string]any{
+			"tags":  []any{"v{{ .Vars.version }}", "latest"},
+			"force": true,
+		},
+	}
+
+	got, err := renderTemplates(payload, data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name": "Mike",
+		"user": "mdb",
+		"nested": map[string]any{
+			"tags":  []any{"v1.2.3", "latest"},
+			"force": true,
+		},
+	}, got)
+
+	// The templated payload isn't modified.
+	assert.Equal(t, "{{ .Vars.name }}", payload["name"])
+
+	_, err = renderTemplates(map[string]any{"nested": []any{"{{ .Vars.missing }}"}}, data)
+	assert.EqualError(t, err, `template: nested[0]:1:8: executing "nested[0]" at <.Vars.missing>: map has no entry for key "missing"`)
+}
+
+func TestGitContext(t *testing.T) {
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		return runGit(t, dir, args...)
+	}
+
+	git("init", "--initial-branch", "feature")
+	git("commit", "--allow-empty", "--message", "initial")
+	git("tag", "v1.0.0")
+	git("commit", "--allow-empty", "--message", "second")
+	git("remote", "add", "origin", "https://github.com/OWNER/REPO.git")
+	sha := git("rev-parse", "HEAD")
+
+	data := &templateData{Git: &gitContext{dir: dir}}
+	got, err := renderTemplates("{{ .Git.Branch }} {{ .Git.SHA }} {{ .Git.Tag }} {{ .Git.RemoteURL }}", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "feature "+sha+" v1.0.0 https://github.com/OWNER/REPO.git", got)
+
+	git("checkout", "--detach")
+	_, err = renderTemplates("{{ .Git.Branch }}", data)
+	assert.EqualError(t, err, `template: :1:7: executing "" at <.Git.Branch>: error calling Branch: git symbolic-ref --short HEAD: fatal: ref HEAD is not a symbolic ref`)
+
+	data = &templateData{Git: &gitContext{dir: filepath.Join(dir, "missing")}}
+	_, err = renderTemplates("{{ .Git.SHA }}", data)
+	assert.ErrorContains(t, err, "error calling SHA: git rev-parse HEAD:")
+}
+
+// runGit runs git in dir as a test author, returning its trimmed output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(),
+		"GIT_AUTHOR_NAME=mdb", "GIT_AUTHOR_EMAIL=mdb@example.com",
+		"GIT_COMMITTER_NAME=mdb", "GIT_COMMITTER_EMAIL=mdb@example.com")
+	out, err := cmd.Output()
+	assert.NoError(t, err)
+
+	return strings.TrimSpace(string(out))
+}
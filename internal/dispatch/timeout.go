@@ -0,0 +1,70 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+)
+
+// errWatchTimeout is returned when watching runs exceeds --timeout.
+var errWatchTimeout = errors.New("timed out")
+
+// watchTimeoutError is returned when watching runs exceeds --timeout, whether
+// or not the runs were then cancelled.
+type watchTimeoutError struct {
+	runIDs    []int64
+	timeout   time.Duration
+	cancelled bool
+}
+
+func (e *watchTimeoutError) Error() string {
+	if e.cancelled {
+		return fmt.Sprintf("timed out after %s; cancelled %s", e.timeout, formatRunIDs(e.runIDs))
+	}
+
+	return fmt.Sprintf("timed out after %s; %s left running", e.timeout, formatRunIDs(e.runIDs))
+}
+
+func (e *watchTimeoutError) ExitCode() int {
+	return exitWatchTimeout
+}
+
+// handleTimeout prints the last known state of the jobs of the runs, which
+// were being watched when --timeout elapsed, and cancels the runs if
+// --cancel-on-timeout is set.
+func handleTimeout(opts *dispatchOptions, client *cliapi.Client, runs []*shared.Run, stops *watchStops) error {
+	cs := opts.io.ColorScheme()
+	out := messageWriter(opts)
+
+	timedOut := &watchTimeoutError{timeout: opts.timeout}
+	for _, run := range runs {
+		timedOut.runIDs = append(timedOut.runIDs, run.ID)
+
+		fmt.Fprintf(out, "Timed out after %s watching run %d, which is %s\n", opts.timeout, run.ID, run.Status)
+
+		// Runs that were only waited for haven't had their jobs fetched.
+		jobs, err := shared.GetJobs(client, opts.repo, run, 0)
+		if err != nil {
+			fmt.Fprintf(opts.io.ErrOut, "%s unable to show the state of its jobs: %s\n", cs.WarningIcon(), err)
+			continue
+		}
+
+		if len(jobs) > 0 {
+			fmt.Fprintln(out, shared.RenderJobs(cs, jobs, true))
+		}
+	}
+
+	if !opts.cancelOnTimeout {
+		return timedOut
+	}
+
+	if err := cancelRuns(opts, client, runs, stops); err != nil {
+		return err
+	}
+	timedOut.cancelled = true
+
+	return timedOut
+}
@@ -0,0 +1,124 @@
+package dispatch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/run/shared"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderResultTimeout(t *testing.T) {
+	registerJobs := func(reg *httpmock.Registry) {
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/actions/runs/123/jobs"),
+			httpmock.StringResponse(`{
+				"total_count": 1,
+				"jobs": [{
+					"id": 1,
+					"name": "build",
+					"status": "in_progress",
+					"started_at": "2020-01-20T17:42:40Z",
+					"steps": [{
+						"name": "Test",
+						"status": "in_progress",
+						"number": 1
+					}]
+				}]
+			}`))
+	}
+
+	registerAnnotations := func(reg *httpmock.Registry) {
+		reg.Register(
+			httpmock.REST("GET", "repos/OWNER/REPO/check-runs/1/annotations"),
+			httpmock.StringResponse("[]"))
+	}
+
+	tests := []struct {
+		name            string
+		waitForRunOnly  bool
+		cancelOnTimeout bool
+		httpStubs       func(*httpmock.Registry)
+		wantOut         string
+		errMsg          string
+	}{
+		{
+			name: "left running",
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRunStubs(reg, "in_progress", "")
+				registerJobs(reg)
+				registerAnnotations(reg)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+* Run foo (123) in_progress
+* Job build (ID 1) started
+Timed out after 1ns watching run 123, which is in_progress
+* build (ID 1)
+  * Test
+`,
+			errMsg: "timed out after 1ns; run 123 left running",
+		}, {
+			name:            "cancelled with --cancel-on-timeout",
+			cancelOnTimeout: true,
+			httpStubs: func(reg *httpmock.Registry) {
+				registerRunStubs(reg, "in_progress", "")
+				registerJobs(reg)
+				registerAnnotations(reg)
+				registerCancelRunStubs(reg)
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+* Run foo (123) in_progress
+* Job build (ID 1) started
+Timed out after 1ns watching run 123, which is in_progress
+* build (ID 1)
+  * Test
+Cancelling run 123...
+X Run foo (123) completed with 'cancelled'
+`,
+			errMsg: "timed out after 1ns; cancelled run 123",
+		}, {
+			name:           "waiting for the run only",
+			waitForRunOnly: true,
+			httpStubs:      registerJobs,
+			wantOut: `Timed out after 1ns watching run 123, which is in_progress
+* build (ID 1)
+  * Test
+`,
+			errMsg: "timed out after 1ns; run 123 left running",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			tt.httpStubs(reg)
+
+			ios, _, stdout, _ := iostreams.Test()
+
+			opts := &dispatchOptions{
+				repo:            &ghRepo{Owner: "OWNER", Name: "REPO"},
+				httpClient:      &http.Client{Transport: reg},
+				io:              ios,
+				waitForRunOnly:  tt.waitForRunOnly,
+				timeout:         time.Nanosecond,
+				cancelOnTimeout: tt.cancelOnTimeout,
+			}
+
+			client := cliapi.NewClientFromHTTP(opts.httpClient)
+			err := renderResult(opts, client, &shared.Run{
+				ID:         123,
+				WorkflowID: 456,
+				Status:     shared.InProgress,
+				JobsURL:    "https://api.github.com/repos/OWNER/REPO/actions/runs/123/jobs",
+			})
+			assert.EqualError(t, err, tt.errMsg)
+			assert.Equal(t, exitWatchTimeout, ExitCode(err))
+			assert.Equal(t, tt.wantOut, stdout.String())
+
+			reg.Verify(t)
+		})
+	}
+}
@@ -2,12 +2,111 @@ package dispatch
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/cli/cli/v2/pkg/cmdutil"
 	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/spf13/cobra"
 )
 
+const defaultDiscoveryTimeout = 5 * time.Minute
+
+// defaultFailedLogLines is the default number of lines of each failed step's
+// log printed with --failed-logs.
+const defaultFailedLogLines = 20
+
+// defaultMaxConcurrency is the default number of dispatch events sent, or
+// runs polled, at once.
+const defaultMaxConcurrency = 4
+
+// prompter prompts for interactive input.
+type prompter interface {
+	Select(prompt, defaultValue string, options []string) (int, error)
+	Input(prompt, defaultValue string) (string, error)
+	Confirm(prompt string, defaultValue bool) (bool, error)
+}
+
 type dispatchOptions struct {
-	repo       *ghRepo
-	httpClient *http.Client
-	io         *iostreams.IOStreams
+	repo             *ghRepo
+	httpClient       *http.Client
+	io               *iostreams.IOStreams
+	prompter         prompter
+	dispatchIDKey    string
+	discoveryTimeout time.Duration
+	interval         int
+	noWatch          bool
+	waitForRunOnly   bool
+	exporter         cmdutil.Exporter
+	neutralAsSuccess bool
+	logs             bool
+	failedLogs       bool
+	failedLogLines   int
+	// downloadArtifacts is the glob matching the names of the artifacts to
+	// download once the run completes; none are downloaded if it's empty.
+	downloadArtifacts string
+	artifactsDir      string
+	maxConcurrency    int
+	// templates enables rendering the inputs' or client payload's string
+	// values as templates, which vars also implies.
+	templates bool
+	vars      []string
+	// cancelOnInterrupt cancels the watched run on Ctrl+C without prompting.
+	cancelOnInterrupt bool
+	// timeout is how long to watch, or wait for, the run; 0 is indefinitely.
+	timeout         time.Duration
+	cancelOnTimeout bool
+	// retry is the number of times a failed run is re-run.
+	retry               int
+	retryFailedJobsOnly bool
+}
+
+// refreshInterval returns the interval in seconds at which runs are polled,
+// which is defaultInterval unless --interval is positive.
+func (opts *dispatchOptions) refreshInterval() int {
+	if opts.interval <= 0 {
+		return defaultInterval
+	}
+
+	return opts.interval
+}
+
+// addDispatchFlags adds the flags shared by the repository and workflow
+// commands to cmd, binding their values to opts.
+func addDispatchFlags(cmd *cobra.Command, opts *dispatchOptions) {
+	cmd.Flags().DurationVar(&opts.discoveryTimeout, "discovery-timeout", defaultDiscoveryTimeout, "How long to wait for the dispatched GitHub Actions run to appear. 0 waits indefinitely.")
+	cmd.Flags().IntVar(&opts.interval, "interval", defaultInterval, "Refresh interval in seconds when watching the GitHub Actions run.")
+	cmd.Flags().BoolVar(&opts.noWatch, "no-watch", false, "Print the resulting GitHub Actions run's ID and URL without watching it.")
+	cmd.Flags().BoolVar(&opts.waitForRunOnly, "wait-for-run-only", false, "Wait for the resulting GitHub Actions run to complete without watching it, then print its ID and URL.")
+	cmd.MarkFlagsMutuallyExclusive("no-watch", "wait-for-run-only")
+	cmd.Flags().BoolVar(&opts.neutralAsSuccess, "neutral-as-success", false, "Exit successfully when the GitHub Actions run concludes as 'skipped' or 'neutral'.")
+	cmd.Flags().BoolVar(&opts.logs, "logs", false, "Print the full logs of the GitHub Actions run's failed steps and, when stdout isn't a terminal, each job's log once it completes.")
+	cmd.MarkFlagsMutuallyExclusive("logs", "no-watch")
+	cmd.MarkFlagsMutuallyExclusive("logs", "wait-for-run-only")
+	cmd.Flags().BoolVar(&opts.failedLogs, "failed-logs", false, "Print the last lines of the logs of the GitHub Actions run's failed steps once it completes. Defaults to true when stdout is a terminal.")
+	cmd.Flags().IntVar(&opts.failedLogLines, "failed-log-lines", defaultFailedLogLines, "The number of lines of each failed step's log to print with --failed-logs.")
+	cmd.Flags().StringVar(&opts.downloadArtifacts, "download-artifacts", "", "Download the artifacts whose names match the glob `pattern` once the GitHub Actions run completes, or all of them if no pattern is given.")
+	cmd.Flags().Lookup("download-artifacts").NoOptDefVal = "*"
+	cmd.Flags().StringVar(&opts.artifactsDir, "dir", ".", "The directory in which to download artifacts, each into a subdirectory named after it.")
+	cmd.MarkFlagsMutuallyExclusive("download-artifacts", "no-watch")
+	cmd.Flags().IntVar(&opts.maxConcurrency, "max-concurrency", defaultMaxConcurrency, "The maximum number of dispatch events to send, or runs to poll, at once when targeting several repositories or applying a plan.")
+	cmd.Flags().BoolVar(&opts.cancelOnInterrupt, "cancel-on-interrupt", false, "Cancel the GitHub Actions run when watching it is interrupted with Ctrl+C, rather than prompting on a terminal or leaving it running otherwise.")
+	cmd.MarkFlagsMutuallyExclusive("cancel-on-interrupt", "no-watch")
+	cmd.MarkFlagsMutuallyExclusive("cancel-on-interrupt", "wait-for-run-only")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 0, "How long to watch, or wait for, the GitHub Actions run before giving up. 0 waits indefinitely.")
+	cmd.Flags().BoolVar(&opts.cancelOnTimeout, "cancel-on-timeout", false, "Cancel the GitHub Actions run if --timeout elapses before it completes.")
+	cmd.MarkFlagsMutuallyExclusive("timeout", "no-watch")
+	cmd.Flags().IntVar(&opts.retry, "retry", 0, "Re-run the GitHub Actions run up to `N` times while it concludes with 'failure' or 'timed_out'.")
+	cmd.Flags().BoolVar(&opts.retryFailedJobsOnly, "retry-failed-jobs-only", false, "Only re-run the failed jobs of the GitHub Actions run when retrying.")
+	cmd.MarkFlagsMutuallyExclusive("retry", "no-watch")
+	cmd.Flags().BoolVar(&opts.templates, "templates", false, "Render the string values of the inputs or client payload as Go templates with .Vars, .Env, and .Git data.")
+	cmd.Flags().StringArrayVar(&opts.vars, "var", nil, "Set a template variable in `key=value` format; implies --templates.")
+	cmdutil.AddJSONFlags(cmd, &opts.exporter, runResultFields)
+}
+
+// applyTTYDefaults enables the flags that default to on when stdout is a
+// terminal, unless they were set explicitly.
+func applyTTYDefaults(cmd *cobra.Command, opts *dispatchOptions) {
+	if !cmd.Flags().Changed("failed-logs") {
+		opts.failedLogs = opts.io.IsStdoutTTY()
+	}
 }
@@ -3,7 +3,9 @@ package dispatch
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/MakeNowJust/heredoc"
@@ -12,45 +14,93 @@ import (
 	"github.com/cli/cli/v2/pkg/cmd/workflow/shared"
 	"github.com/cli/cli/v2/pkg/iostreams"
 	ghapi "github.com/cli/go-gh/v2/pkg/api"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
 	"github.com/spf13/cobra"
 )
 
 type workflowDispatchRequest struct {
-	Inputs any    `json:"inputs"`
-	Ref    string `json:"ref"`
+	Inputs           any    `json:"inputs,omitempty"`
+	Ref              string `json:"ref"`
+	ReturnRunDetails bool   `json:"return_run_details,omitempty"`
+}
+
+// workflowDispatchResponse holds the details of the created run, which the
+// workflow dispatch API returns when return_run_details is requested.
+type workflowDispatchResponse struct {
+	WorkflowRunID int64  `json:"workflow_run_id"`
+	RunURL        string `json:"run_url"`
+	HTMLURL       string `json:"html_url"`
 }
 
 type workflowDispatchOptions struct {
 	inputs   any
 	ref      string
 	workflow string
+	// currentBranch sends the event to the local checkout's current branch
+	// rather than ref.
+	currentBranch bool
+	// matchHeadSHA only matches a discovered run whose head commit is the
+	// ref's commit at the time of dispatch.
+	matchHeadSHA bool
+	// skipValidation sends inputs without fetching the workflow file to
+	// validate them.
+	skipValidation bool
 	dispatchOptions
 }
 
 // NewCmdWorkflow returns a new workflow command.
 func NewCmdWorkflow() *cobra.Command {
 	var (
-		workflowInputs string
+		workflowInputs payloadFlags
 		workflowName   string
 		workflowRef    string
+		currentBranch  bool
+		matchHeadSHA   bool
+		skipValidation bool
+		dOptions       dispatchOptions
 	)
 
 	cmd := &cobra.Command{
 		Use: heredoc.Doc(`
 		workflow \
 			--repo [owner/repo] \
-			--inputs [json-string] \
+			--inputs [json-string | @file | -] \
 			--workflow [workflow-file-name.yaml]
 	`),
 		Short: "Send a workflow dispatch event and watch the resulting GitHub Actions run",
 		Long: heredoc.Doc(`
 		This command sends a workflow dispatch event and attempts to find and watch the
-		resulting GitHub Actions run whose file name or ID is specified as '--workflow'.
+		resulting GitHub Actions run whose file name or ID is specified as '--workflow'. If
+		'--workflow' is omitted and the terminal is interactive, the command prompts for one of
+		the repository's active workflows with a workflow_dispatch trigger.
+
+		Before sending the event, the command validates the inputs against those declared by
+		the workflow's workflow_dispatch 'on' trigger at the specified ref. When no inputs
+		are specified and the terminal is interactive, the command prompts for each declared
+		input instead, pre-filling its default. '--skip-validation' skips both, as does a token
+		that can't read the workflow file, which is reported as a warning.
+
+		The event is sent to '--ref', a branch or tag name or a full ref such as
+		'refs/heads/main', which must exist in the repository. As events can't be sent to a
+		commit, a commit SHA '--ref' is resolved to a branch whose head it is. If '--ref' is
+		omitted, the event is sent to the repository's default branch or, with
+		'--current-branch', to the branch checked out in the current directory, provided the
+		repository's branch of the same name is at the same commit.
+
+		Note that, by default, the command is vulnerable to race conditions and may watch an
+		unrelated GitHub Actions workflow run in the event that multiple runs of the specified
+		workflow are running concurrently on the same ref. '--match-head-sha' narrows the
+		discovered runs to those of the ref's commit at the time of dispatch.
 
-		Note that the command assumes the specified workflow supports a workflow_dispatch
-		'on' trigger. Also note that the command is vulnerable to race conditions and may
-		watch an unrelated GitHub Actions workflow run in the event that multiple runs of
-		the specified workflow are running concurrently.
+		To avoid this, specify '--dispatch-id-key'. The command then injects a generated
+		dispatch ID into the inputs under that key and only watches a run whose display
+		title, job names, or step names contain the ID. The workflow must declare the input
+		and surface it, for example via 'run-name'.
+
+		To send the event to several repositories, repeat '--repo', or specify '--repos-file'
+		or '--org' and '--topic'. The command then renders a combined status table of their
+		runs, prints a summary row for each repository, and exits with the code of the first
+		repository whose dispatch failed or whose run didn't succeed.
 	`),
 		Example: heredoc.Doc(`
 		gh dispatch workflow \
@@ -58,54 +108,126 @@ func NewCmdWorkflow() *cobra.Command {
 			--inputs '{"name": "Mike"}' \
 			--workflow workflow_dispatch.yaml
 
-		# Specify a workflow ref other than 'main'
+		# Specify a workflow ref other than the repository's default branch
 		gh dispatch workflow \
 			--repo mdb/gh-dispatch \
 			--inputs '{"name": "Mike"}' \
 			--workflow workflow_dispatch.yaml \
 			--ref my-feature-branch
+
+		# Send the event to the pushed branch checked out in the current directory
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--inputs '{"name": "Mike"}' \
+			--workflow workflow_dispatch.yaml \
+			--current-branch
+
+		# Prompt for the workflow's declared inputs
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--workflow workflow_dispatch.yaml
+
+		# Build the inputs from fields, reading a value from a file
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--workflow workflow_dispatch.yaml \
+			--raw-field name=Mike \
+			-F greeting=@greeting.txt
+
+		# Read the inputs from a JSON file
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--inputs @inputs.json \
+			--workflow workflow_dispatch.yaml
+
+		# Correlate the dispatch with its run via a 'dispatch_id' input
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--inputs '{"name": "Mike"}' \
+			--workflow workflow_dispatch.yaml \
+			--dispatch-id-key dispatch_id
+
+		# Print the resulting run's ID and URL as JSON without watching it
+		gh dispatch workflow \
+			--repo mdb/gh-dispatch \
+			--inputs '{"name": "Mike"}' \
+			--workflow workflow_dispatch.yaml \
+			--no-watch \
+			--json databaseId,url,workflowDatabaseId
 	`),
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := getRepoOption(cmd)
+			ios := iostreams.System()
+			wInputs, err := workflowInputs.parse("inputs", ios, false)
 			if err != nil {
 				return err
 			}
 
-			b := []byte(workflowInputs)
-			var wInputs any
-			json.Unmarshal(b, &wInputs)
-
-			ios := iostreams.System()
 			ghClient, err := ghapi.DefaultHTTPClient()
 			if err != nil {
 				return err
 			}
-			dOptions := dispatchOptions{
-				repo:       repo,
-				httpClient: ghClient,
-				io:         ios,
+
+			repos, err := getRepoOptions(cmd, ios, ghClient)
+			if err != nil {
+				return err
+			}
+			dOptions.repo = repos[0]
+			dOptions.httpClient = ghClient
+			dOptions.io = ios
+			dOptions.prompter = ghprompter.New(os.Stdin, os.Stdout, os.Stderr)
+			applyTTYDefaults(cmd, &dOptions)
+
+			if len(repos) > 1 {
+				if workflowName == "" {
+					return errors.New("--workflow required when targeting multiple repositories")
+				}
+
+				return multiRepoDispatchRun(&dOptions, repos, func(client *cliapi.Client, opts *dispatchOptions) ([]*runShared.Run, error) {
+					run, err := dispatchWorkflow(client, &workflowDispatchOptions{
+						inputs:          wInputs,
+						ref:             workflowRef,
+						workflow:        workflowName,
+						currentBranch:   currentBranch,
+						matchHeadSHA:    matchHeadSHA,
+						skipValidation:  skipValidation,
+						dispatchOptions: *opts,
+					})
+					if err != nil {
+						return nil, err
+					}
+
+					return []*runShared.Run{run}, nil
+				})
 			}
 
 			return workflowDispatchRun(&workflowDispatchOptions{
 				inputs:          wInputs,
 				ref:             workflowRef,
 				workflow:        workflowName,
+				currentBranch:   currentBranch,
+				matchHeadSHA:    matchHeadSHA,
+				skipValidation:  skipValidation,
 				dispatchOptions: dOptions,
 			})
 		},
 	}
 
-	// TODO: how does the 'gh run' command represent inputs?
-	// Is it worth better emulating its interface?
-	cmd.Flags().StringVarP(&workflowInputs, "inputs", "i", "", "The workflow dispatch inputs JSON string.")
-	cmd.MarkFlagRequired("inputs")
+	cmd.Flags().StringVarP(&workflowInputs.json, "inputs", "i", "", "The workflow dispatch inputs JSON string, a JSON file path prefixed with '@', or '-' to read from stdin.")
+	// The fields follow 'gh api' and 'gh workflow run', except that -f was
+	// --ref's shorthand first, so --raw-field has none.
+	cmd.Flags().StringArrayVar(&workflowInputs.rawFields, "raw-field", nil, "Add a string input in `key=value` format.")
+	cmd.Flags().StringArrayVarP(&workflowInputs.fields, "field", "F", nil, "Add a string input in `key=value` format, reading the value from a file via '@path' or stdin via '@-'.")
 	// TODO: how does the 'gh run' command represent workflow?
 	// Is it worth better emulating its interface?
-	cmd.Flags().StringVarP(&workflowName, "workflow", "w", "", "The resulting GitHub Actions workflow name.")
-	cmd.MarkFlagRequired("workflow")
-	// TODO: how does the 'gh run' command represent ref?
-	// Is it worth better emulating its interface?
-	cmd.Flags().StringVarP(&workflowRef, "ref", "f", "main", "The git reference for the workflow. Can be a branch or tag name.")
+	cmd.Flags().StringVarP(&workflowName, "workflow", "w", "", "The resulting GitHub Actions workflow name; prompted for if omitted.")
+	cmd.Flags().StringVarP(&workflowRef, "ref", "f", "", "The git reference for the workflow: a branch or tag name, a full ref, or the commit SHA at a branch's head. Defaults to the repository's default branch.")
+	cmd.Flags().BoolVar(&currentBranch, "current-branch", false, "Send the event to the current directory's checked out branch, which must be pushed.")
+	cmd.MarkFlagsMutuallyExclusive("ref", "current-branch")
+	cmd.Flags().BoolVar(&matchHeadSHA, "match-head-sha", false, "Only watch a discovered run whose head commit is the ref's commit at the time of dispatch.")
+	cmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Skip fetching the workflow file to validate the inputs, which requires the 'contents: read' permission.")
+	cmd.Flags().StringVar(&dOptions.dispatchIDKey, "dispatch-id-key", "", "The workflow input in which to send a generated dispatch ID used to identify the resulting run.")
+	addDispatchFlags(cmd, &dOptions)
 
 	return cmd
 }
@@ -113,37 +235,131 @@ func NewCmdWorkflow() *cobra.Command {
 func workflowDispatchRun(opts *workflowDispatchOptions) error {
 	ghClient := cliapi.NewClientFromHTTP(opts.httpClient)
 
-	var buf bytes.Buffer
-	err := json.NewEncoder(&buf).Encode(workflowDispatchRequest{
-		Inputs: opts.inputs,
-		Ref:    opts.ref,
-	})
+	run, err := dispatchWorkflow(ghClient, opts)
 	if err != nil {
 		return err
 	}
 
-	var in any
-	dispatchedAt := time.Now()
-	err = ghClient.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/actions/workflows/%s/dispatches", opts.repo.RepoFullName(), opts.workflow), &buf, &in)
+	return renderResult(&opts.dispatchOptions, ghClient, run)
+}
+
+// dispatchWorkflow sends the workflow dispatch event and returns the resulting
+// run once it appears.
+func dispatchWorkflow(client *cliapi.Client, opts *workflowDispatchOptions) (*runShared.Run, error) {
+	ref, err := resolveWorkflowRef(client, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var wf shared.Workflow
-	err = ghClient.REST(opts.repo.RepoHost(), "GET", fmt.Sprintf("repos/%s/actions/workflows/%s", opts.repo.RepoFullName(), opts.workflow), nil, &wf)
+	if opts.workflow == "" {
+		if opts.prompter == nil || !opts.io.CanPrompt() {
+			return nil, errors.New("--workflow required when not running interactively")
+		}
+
+		selected, err := selectWorkflow(opts.prompter, client, opts.io, opts.repo, ref.ref, func(triggers *workflowTriggers) bool {
+			return triggers.workflowDispatch
+		})
+		if err != nil {
+			return nil, err
+		}
+		wf = *selected
+	} else {
+		err := client.REST(opts.repo.RepoHost(), "GET", fmt.Sprintf("repos/%s/actions/workflows/%s", opts.repo.RepoFullName(), opts.workflow), nil, &wf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var triggers *workflowTriggers
+	if !opts.skipValidation {
+		triggers, err = getWorkflowTriggers(client, opts.repo, &wf, ref.ref)
+		if isForbiddenOrNotFound(err) {
+			// Tokens without the contents permission, such as fine-grained
+			// tokens only granted actions: write, can still dispatch.
+			fmt.Fprintf(opts.io.ErrOut, "%s skipping input validation: %s\n", opts.io.ColorScheme().WarningIcon(), err)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if triggers != nil && !triggers.workflowDispatch {
+		return nil, fmt.Errorf("workflow %s does not have a workflow_dispatch trigger", wf.Path)
+	}
+
+	inputs := opts.inputs
+	if inputs == nil && triggers != nil && opts.prompter != nil && opts.io.CanPrompt() && len(triggers.inputs) > 0 {
+		inputs, err = promptInputs(opts.prompter, client, opts.repo, triggers.inputs, opts.dispatchIDKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	inputs, err = applyTemplates(&opts.dispatchOptions, inputs)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid inputs: %w", err)
+	}
+
+	var dispatchID string
+	if opts.dispatchIDKey != "" {
+		dispatchID = newDispatchID()
+
+		inputs, err = injectDispatchID(inputs, opts.dispatchIDKey, dispatchID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inputs: %w", err)
+		}
 	}
 
-	runID, err := getRunID(ghClient, opts.repo, "workflow_dispatch", wf.ID, dispatchedAt)
+	if triggers != nil {
+		err = validateWorkflowInputs(client, opts.repo, &wf, triggers, inputs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	returnRunDetails, err := supportsRunDetails(client, opts.repo.RepoHost())
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to detect workflow dispatch API support: %w", err)
+	}
+
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(workflowDispatchRequest{
+		Inputs:           inputs,
+		Ref:              ref.ref,
+		ReturnRunDetails: returnRunDetails,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The API responds with 204 No Content, rather than the run's details, if
+	// they weren't requested or, occasionally, even if they were.
+	var dispatchResponse workflowDispatchResponse
+	dispatchedAt := time.Now()
+	err = client.REST(opts.repo.RepoHost(), "POST", fmt.Sprintf("repos/%s/actions/workflows/%d/dispatches", opts.repo.RepoFullName(), wf.ID), &buf, &dispatchResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := dispatchResponse.WorkflowRunID
+	if runID == 0 {
+		runID, err = getRunID(client, opts.repo, runFilter{
+			event:        "workflow_dispatch",
+			workflowID:   wf.ID,
+			dispatchedAt: dispatchedAt,
+			dispatchID:   dispatchID,
+			headBranch:   ref.headBranch,
+			headSHA:      ref.sha,
+		}, opts.discoveryTimeout)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	run, err := runShared.GetRun(ghClient, opts.repo, fmt.Sprintf("%d", runID), 0)
+	run, err := runShared.GetRun(client, opts.repo, fmt.Sprintf("%d", runID), 0)
 	if err != nil {
-		return fmt.Errorf("failed to get run: %w", err)
+		return nil, fmt.Errorf("failed to get run: %w", err)
 	}
 
-	return render(opts.io, ghClient, opts.repo, run)
+	return run, nil
 }
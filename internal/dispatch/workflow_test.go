@@ -1,13 +1,17 @@
 package dispatch
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/cli/cli/v2/pkg/cmdutil"
 	"github.com/cli/cli/v2/pkg/httpmock"
 	"github.com/cli/cli/v2/pkg/iostreams"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,13 +24,17 @@ func TestWorkflowDispatchRun(t *testing.T) {
 	workflow := "workflow.yaml"
 	event := "workflow_dispatch"
 
+	newDispatchID = func() string { return "some-dispatch-id" }
+	defer func() { newDispatchID = uuid.NewString }()
+
 	createMockRegistry := func(reg *httpmock.Registry, conclusion, jobsResponse string) {
 		reg.Register(
-			httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/%s/dispatches", repo, "workflow.yaml")),
+			httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
 			httpmock.RESTPayload(201, "{}", func(params map[string]any) {
 				assert.Equal(t, map[string]any{
-					"inputs": "{\"foo\": \"bar\"}",
-					"ref":    "",
+					"inputs":             map[string]any{"foo": "bar"},
+					"ref":                "main",
+					"return_run_details": true,
 				}, params)
 			}))
 
@@ -34,6 +42,10 @@ func TestWorkflowDispatchRun(t *testing.T) {
 			httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
 			httpmock.StringResponse(getWorkflowResponse))
 
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+			httpmock.StringResponse(getWorkflowContentResponse))
+
 		reg.Register(
 			httpmock.GraphQL("query UserCurrent{viewer{login}}"),
 			httpmock.StringResponse(currentUserResponse))
@@ -77,6 +89,7 @@ func TestWorkflowDispatchRun(t *testing.T) {
 			httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
 			httpmock.StringResponse(fmt.Sprintf(`{
 				"id": 123,
+				"name": "foo",
 				"workflow_id": 456,
 				"event": "workflow_dispatch",
 				"status": "completed",
@@ -94,23 +107,26 @@ func TestWorkflowDispatchRun(t *testing.T) {
 	}
 
 	tests := []struct {
-		name      string
-		opts      *workflowDispatchOptions
-		httpStubs func(*httpmock.Registry)
-		wantErr   bool
-		errMsg    string
-		wantOut   string
+		name       string
+		opts       *workflowDispatchOptions
+		httpStubs  func(*httpmock.Registry)
+		tty        bool
+		wantErr    bool
+		errMsg     string
+		wantOut    string
+		wantErrOut string
 	}{
 		{
 			name: "successful workflow run",
 			opts: &workflowDispatchOptions{
-				inputs:   `{"foo": "bar"}`,
+				inputs:   map[string]any{"foo": "bar"},
 				workflow: workflow,
 			},
 			httpStubs: func(reg *httpmock.Registry) {
 				createMockRegistry(reg, "success", getJobsResponse)
 			},
-			wantOut: `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
+			tty: true,
+			wantOut: "\x1b[0;0H\x1b[J" + `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
 
 https://github.com/OWNER/REPO/actions/runs/123
 
@@ -121,39 +137,414 @@ JOBS
 ✓ build in 1m59s (ID 123)
   ✓ Run actions/checkout@v2
   ✓ Test
+
+✓ foo (123) completed with 'success'
 `,
 		}, {
 			name: "unsuccessful workflow run",
 			opts: &workflowDispatchOptions{
-				inputs:   `{"foo": "bar"}`,
+				inputs:   map[string]any{"foo": "bar"},
 				workflow: workflow,
 			},
 			httpStubs: func(reg *httpmock.Registry) {
 				createMockRegistry(reg, "failure", getFailingJobsResponse)
 			},
-			wantOut: `Refreshing run status every 2 seconds. Press Ctrl+C to quit.
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+✓ Job build (ID 123) completed with 'success' in 1m59s
+  X Test
+X Run foo (123) completed with 'failure'
+`,
+			wantErr: true,
+			errMsg:  "run 123 completed with 'failure'",
+		}, {
+			name: "workflow run correlated by dispatch ID",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"foo": "bar"},
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					dispatchIDKey: "dispatch_id",
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.RESTPayload(201, "{}", func(params map[string]any) {
+						assert.Equal(t, map[string]any{
+							"inputs": map[string]any{
+								"foo":         "bar",
+								"dispatch_id": "some-dispatch-id",
+							},
+							"ref":                "main",
+							"return_run_details": true,
+						}, params)
+					}))
 
-https://github.com/OWNER/REPO/actions/runs/123
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
 
-X  foo · 123
-Triggered via workflow_dispatch 
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
 
-JOBS
-✓ build in 1m59s (ID 123)
-  ✓ Run actions/checkout@v2
-  X Test
+				reg.Register(
+					httpmock.GraphQL("query UserCurrent{viewer{login}}"),
+					httpmock.StringResponse(currentUserResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456/runs", repo)),
+					httpmock.StringResponse(fmt.Sprintf(getCorrelatedWorkflowRunsResponse, event, repo, "some-dispatch-id")))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/124/jobs", repo)),
+					httpmock.StringResponse(getJobsResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(fmt.Sprintf(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "completed",
+						"conclusion": "success",
+						"jobs_url": "https://api.github.com/repos/%s/actions/runs/123/jobs"
+					}`, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123/jobs", repo)),
+					httpmock.StringResponse(getJobsResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/check-runs/123/annotations", repo)),
+					httpmock.StringResponse("[]"))
+			},
+			wantOut: `Watching https://github.com/OWNER/REPO/actions/runs/123
+✓ Job build (ID 123) completed with 'success' in 1m59s
+✓ Run foo (123) completed with 'success'
 `,
+		}, {
+			name: "no-watch workflow run with JSON output",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"foo": "bar"},
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+					exporter: func() cmdutil.Exporter {
+						exporter := cmdutil.NewJSONExporter()
+						exporter.SetFields([]string{"databaseId", "status", "workflowDatabaseId", "workflowName"})
+						return exporter
+					}(),
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.StringResponse("{}"))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
+
+				reg.Register(
+					httpmock.GraphQL("query UserCurrent{viewer{login}}"),
+					httpmock.StringResponse(currentUserResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456/runs", repo)),
+					httpmock.StringResponse(fmt.Sprintf(getWorkflowRunsResponse, event, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows", repo)),
+					httpmock.StringResponse(getWorkflowsResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "queued"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `{"databaseId":123,"status":"queued","workflowDatabaseId":456,"workflowName":"foo"}
+`,
+		}, {
+			name: "run ID returned by the workflow dispatch API",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"foo": "bar"},
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.StringResponse(fmt.Sprintf(`{
+						"workflow_run_id": 123,
+						"run_url": "https://api.github.com/repos/%[1]s/actions/runs/123",
+						"html_url": "https://github.com/%[1]s/actions/runs/123"
+					}`, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "queued"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
+`,
+		}, {
+			name: "workflow file inaccessible to the token",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"fooo": "bar"},
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.StringResponse(fmt.Sprintf(`{
+						"workflow_run_id": 123,
+						"run_url": "https://api.github.com/repos/%[1]s/actions/runs/123",
+						"html_url": "https://github.com/%[1]s/actions/runs/123"
+					}`, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StatusStringResponse(http.StatusForbidden, `{"message": "Resource not accessible by personal access token"}`))
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "queued"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
+`,
+			wantErrOut: "! skipping input validation: failed to get workflow file .github/workflows/workflow.yaml: HTTP 403 (https://api.github.com/repos/OWNER/REPO/contents/.github/workflows/workflow.yaml?ref=main)\n",
+		}, {
+			name: "--skip-validation",
+			opts: &workflowDispatchOptions{
+				inputs:         map[string]any{"fooo": "bar"},
+				workflow:       workflow,
+				skipValidation: true,
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.StringResponse(fmt.Sprintf(`{
+						"workflow_run_id": 123,
+						"run_url": "https://api.github.com/repos/%[1]s/actions/runs/123",
+						"html_url": "https://github.com/%[1]s/actions/runs/123"
+					}`, repo)))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "queued"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
+`,
+		}, {
+			name: "no inputs",
+			opts: &workflowDispatchOptions{
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					noWatch: true,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(fmt.Sprintf(`{"content": "%s"}`, base64.StdEncoding.EncodeToString([]byte("on: workflow_dispatch")))))
+
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.RESTPayload(200, `{"workflow_run_id": 123}`, func(params map[string]any) {
+						// The API rejects null inputs.
+						assert.Equal(t, map[string]any{
+							"ref":                "main",
+							"return_run_details": true,
+						}, params)
+					}))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/runs/123", repo)),
+					httpmock.StringResponse(`{
+						"id": 123,
+						"workflow_id": 456,
+						"event": "workflow_dispatch",
+						"status": "queued"
+					}`))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+			},
+			wantOut: `Run ID:      123
+URL:         https://github.com/OWNER/REPO/actions/runs/123
+Workflow ID: 456
+`,
+		}, {
+			name: "no run appears before the discovery timeout",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"foo": "bar"},
+				workflow: workflow,
+				dispatchOptions: dispatchOptions{
+					discoveryTimeout: time.Nanosecond,
+				},
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
+					httpmock.StringResponse("{}"))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
+
+				reg.Register(
+					httpmock.GraphQL("query UserCurrent{viewer{login}}"),
+					httpmock.StringResponse(currentUserResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/456/runs", repo)),
+					httpmock.StringResponse(`{"total_count": 0, "workflow_runs": []}`))
+			},
+			wantOut: "",
+			wantErr: true,
+			errMsg:  "no workflow_dispatch run of workflow 456 appeared within 1ns; verify the workflow is enabled and triggered by the dispatched event and ref",
+		}, {
+			name: "invalid inputs",
+			opts: &workflowDispatchOptions{
+				inputs:   map[string]any{"fooo": "bar"},
+				workflow: workflow,
+			},
+			httpStubs: func(reg *httpmock.Registry) {
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
+			},
+			wantOut: "",
 			wantErr: true,
-			errMsg:  "SilentError",
+			errMsg: `invalid inputs for workflow .github/workflows/workflow.yaml:
+  - missing required input 'foo'
+  - unknown input 'fooo'`,
+		}, {
+			name: "no workflow when not interactive",
+			opts: &workflowDispatchOptions{
+				inputs: map[string]any{"foo": "bar"},
+			},
+			httpStubs: func(reg *httpmock.Registry) {},
+			wantOut:   "",
+			wantErr:   true,
+			errMsg:    "--workflow required when not running interactively",
 		}, {
 			name: "malformed JSON response",
 			opts: &workflowDispatchOptions{
-				inputs:   `{"foo": "bar"}`,
+				inputs:   map[string]any{"foo": "bar"},
 				workflow: workflow,
 			},
 			httpStubs: func(reg *httpmock.Registry) {
 				reg.Register(
-					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/%s/dispatches", repo, "workflow.yaml")),
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/actions/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowResponse))
+
+				reg.Register(
+					httpmock.REST("GET", fmt.Sprintf("repos/%s/contents/.github/workflows/workflow.yaml", repo)),
+					httpmock.StringResponse(getWorkflowContentResponse))
+
+				reg.Register(
+					httpmock.REST("POST", fmt.Sprintf("repos/%s/actions/workflows/456/dispatches", repo)),
 					httpmock.StringResponse("{"))
 			},
 			wantOut: "",
@@ -163,10 +554,13 @@ JOBS
 
 	for _, tt := range tests {
 		reg := &httpmock.Registry{}
+		reg.Register(
+			httpmock.REST("GET", fmt.Sprintf("repos/%s", repo)),
+			httpmock.StringResponse(getRepoResponse))
 		tt.httpStubs(reg)
 
-		ios, _, stdout, _ := iostreams.Test()
-		ios.SetStdoutTTY(false)
+		ios, _, stdout, stderr := iostreams.Test()
+		ios.SetStdoutTTY(tt.tty)
 		ios.SetAlternateScreenBufferEnabled(false)
 
 		tt.opts.repo = ghRepo
@@ -187,8 +581,18 @@ JOBS
 			if got := stdout.String(); got != tt.wantOut {
 				t.Errorf("got stdout:\n%q\nwant:\n%q", got, tt.wantOut)
 			}
+			assert.Equal(t, tt.wantErrOut, stderr.String())
 
 			reg.Verify(t)
 		})
 	}
 }
+
+func TestNewCmdWorkflowShorthands(t *testing.T) {
+	flags := NewCmdWorkflow().Flags()
+
+	// -f was --ref's shorthand before --raw-field was added.
+	assert.Equal(t, "ref", flags.ShorthandLookup("f").Name)
+	assert.Equal(t, "", flags.Lookup("raw-field").Shorthand)
+	assert.Equal(t, "field", flags.ShorthandLookup("F").Name)
+}
@@ -0,0 +1,157 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// workflowInput is an input declared by a workflow's workflow_dispatch trigger.
+type workflowInput struct {
+	Name        string   `yaml:"-"`
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Type        string   `yaml:"type"`
+	Options     []string `yaml:"options"`
+}
+
+// workflowTriggers describes the dispatch events that trigger a workflow, as
+// declared by the 'on' key of its workflow file.
+type workflowTriggers struct {
+	workflowDispatch bool
+	// inputs are the workflow_dispatch inputs, in the order they're declared.
+	inputs []workflowInput
+
+	repositoryDispatch bool
+	// repositoryDispatchTypes are the repository_dispatch event types the
+	// workflow is filtered to; all event types trigger it if empty.
+	repositoryDispatchTypes []string
+}
+
+// triggeredByRepositoryDispatch reports whether a repository_dispatch event
+// of the given type triggers the workflow.
+func (t *workflowTriggers) triggeredByRepositoryDispatch(eventType string) bool {
+	if !t.repositoryDispatch {
+		return false
+	}
+
+	return len(t.repositoryDispatchTypes) == 0 || slices.Contains(t.repositoryDispatchTypes, eventType)
+}
+
+// parseWorkflowTriggers parses the dispatch triggers from the content of a
+// workflow file.
+func parseWorkflowTriggers(content []byte) (*workflowTriggers, error) {
+	var workflow struct {
+		On yaml.Node `yaml:"on"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, fmt.Errorf("unable to parse workflow YAML: %w", err)
+	}
+
+	triggers := &workflowTriggers{}
+	on := workflow.On
+
+	switch on.Kind {
+	case yaml.ScalarNode:
+		triggers.workflowDispatch = on.Value == "workflow_dispatch"
+		triggers.repositoryDispatch = on.Value == "repository_dispatch"
+	case yaml.SequenceNode:
+		for _, node := range on.Content {
+			switch node.Value {
+			case "workflow_dispatch":
+				triggers.workflowDispatch = true
+			case "repository_dispatch":
+				triggers.repositoryDispatch = true
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			switch on.Content[i].Value {
+			case "workflow_dispatch":
+				triggers.workflowDispatch = true
+				inputs, err := parseWorkflowInputs(on.Content[i+1])
+				if err != nil {
+					return nil, err
+				}
+				triggers.inputs = inputs
+			case "repository_dispatch":
+				triggers.repositoryDispatch = true
+				types, err := parseRepositoryDispatchTypes(on.Content[i+1])
+				if err != nil {
+					return nil, err
+				}
+				triggers.repositoryDispatchTypes = types
+			}
+		}
+	default:
+		return nil, errors.New("invalid workflow: no 'on' key")
+	}
+
+	return triggers, nil
+}
+
+// parseWorkflowInputs parses the inputs of a workflow_dispatch trigger node,
+// preserving their declared order.
+func parseWorkflowInputs(dispatchNode *yaml.Node) ([]workflowInput, error) {
+	if dispatchNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var inputsNode *yaml.Node
+	for i := 0; i+1 < len(dispatchNode.Content); i += 2 {
+		if dispatchNode.Content[i].Value == "inputs" {
+			inputsNode = dispatchNode.Content[i+1]
+		}
+	}
+
+	if inputsNode == nil || inputsNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	inputs := []workflowInput{}
+	for i := 0; i+1 < len(inputsNode.Content); i += 2 {
+		var input workflowInput
+		if err := inputsNode.Content[i+1].Decode(&input); err != nil {
+			return nil, fmt.Errorf("could not decode workflow input %q: %w", inputsNode.Content[i].Value, err)
+		}
+
+		input.Name = inputsNode.Content[i].Value
+		if input.Type == "" {
+			input.Type = "string"
+		}
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
+
+// parseRepositoryDispatchTypes parses the event types of a repository_dispatch
+// trigger node, which may be a single type or a list of types.
+func parseRepositoryDispatchTypes(dispatchNode *yaml.Node) ([]string, error) {
+	if dispatchNode.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(dispatchNode.Content); i += 2 {
+		if dispatchNode.Content[i].Value != "types" {
+			continue
+		}
+
+		typesNode := dispatchNode.Content[i+1]
+		if typesNode.Kind == yaml.ScalarNode {
+			return []string{typesNode.Value}, nil
+		}
+
+		var types []string
+		if err := typesNode.Decode(&types); err != nil {
+			return nil, fmt.Errorf("could not decode repository_dispatch types: %w", err)
+		}
+
+		return types, nil
+	}
+
+	return nil, nil
+}
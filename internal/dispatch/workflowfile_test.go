@@ -0,0 +1,137 @@
+package dispatch
+
+import (
+	"testing"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkflowTriggers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *workflowTriggers
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "scalar trigger",
+			content: heredoc.Doc(`
+				on: workflow_dispatch
+			`),
+			want: &workflowTriggers{
+				workflowDispatch: true,
+			},
+		}, {
+			name: "sequence trigger",
+			content: heredoc.Doc(`
+				on: [push, workflow_dispatch]
+			`),
+			want: &workflowTriggers{
+				workflowDispatch: true,
+			},
+		}, {
+			name: "no workflow_dispatch trigger",
+			content: heredoc.Doc(`
+				on:
+				  push:
+				    branches: [main]
+			`),
+			want: &workflowTriggers{},
+		}, {
+			name: "workflow_dispatch inputs",
+			content: heredoc.Doc(`
+				on:
+				  workflow_dispatch:
+				    inputs:
+				      name:
+				        description: "The name to address"
+				        required: true
+				      force_fail:
+				        type: boolean
+				        default: false
+				      level:
+				        type: choice
+				        options: [debug, info]
+			`),
+			want: &workflowTriggers{
+				workflowDispatch: true,
+				inputs: []workflowInput{{
+					Name:        "name",
+					Description: "The name to address",
+					Required:    true,
+					Type:        "string",
+				}, {
+					Name:    "force_fail",
+					Type:    "boolean",
+					Default: "false",
+				}, {
+					Name:    "level",
+					Type:    "choice",
+					Options: []string{"debug", "info"},
+				}},
+			},
+		}, {
+			name: "scalar repository_dispatch trigger",
+			content: heredoc.Doc(`
+				on: repository_dispatch
+			`),
+			want: &workflowTriggers{
+				repositoryDispatch: true,
+			},
+		}, {
+			name: "repository_dispatch types",
+			content: heredoc.Doc(`
+				on:
+				  workflow_dispatch:
+				  repository_dispatch:
+				    types: [hello, goodbye]
+			`),
+			want: &workflowTriggers{
+				workflowDispatch:        true,
+				repositoryDispatch:      true,
+				repositoryDispatchTypes: []string{"hello", "goodbye"},
+			},
+		}, {
+			name: "single repository_dispatch type",
+			content: heredoc.Doc(`
+				on:
+				  repository_dispatch:
+				    types: hello
+			`),
+			want: &workflowTriggers{
+				repositoryDispatch:      true,
+				repositoryDispatchTypes: []string{"hello"},
+			},
+		}, {
+			name: "no 'on' key",
+			content: heredoc.Doc(`
+				name: foo
+			`),
+			wantErr: true,
+			errMsg:  "invalid workflow: no 'on' key",
+		}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWorkflowTriggers([]byte(tt.content))
+
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTriggeredByRepositoryDispatch(t *testing.T) {
+	assert.False(t, (&workflowTriggers{workflowDispatch: true}).triggeredByRepositoryDispatch("hello"))
+	assert.True(t, (&workflowTriggers{repositoryDispatch: true}).triggeredByRepositoryDispatch("hello"))
+
+	typed := &workflowTriggers{repositoryDispatch: true, repositoryDispatchTypes: []string{"hello"}}
+	assert.True(t, typed.triggeredByRepositoryDispatch("hello"))
+	assert.False(t, typed.triggeredByRepositoryDispatch("goodbye"))
+}
@@ -0,0 +1,142 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/cmd/workflow/shared"
+	"github.com/cli/cli/v2/pkg/iostreams"
+)
+
+// workflowsDir is the directory of workflow files. Dynamic workflows, which
+// GitHub manages, have paths outside of it.
+const workflowsDir = ".github/workflows/"
+
+// getWorkflowTriggers fetches the workflow file at ref and parses its dispatch
+// triggers. The default branch is used if ref is empty.
+func getWorkflowTriggers(client *cliapi.Client, repo *ghRepo, workflow *shared.Workflow, ref string) (*workflowTriggers, error) {
+	content, err := shared.GetWorkflowContent(client, repo, *workflow, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow file %s: %w", workflow.Path, err)
+	}
+
+	return parseWorkflowTriggers(content)
+}
+
+// resolveWorkflow resolves selector, a workflow file name, ID, or name, to one
+// of the repository's workflows.
+func resolveWorkflow(client *cliapi.Client, repo *ghRepo, selector string) (*shared.Workflow, error) {
+	workflows, err := getWorkflows(client, repo.RepoHost(), repo.RepoFullName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflows: %w", err)
+	}
+
+	matches := []shared.Workflow{}
+	for _, wf := range workflows {
+		if strconv.FormatInt(wf.ID, 10) == selector || wf.Path == selector || wf.Base() == selector || wf.Name == selector {
+			matches = append(matches, wf)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		candidates := []string{}
+		for _, wf := range workflows {
+			candidates = append(candidates, fmt.Sprintf("%s (%s)", wf.Name, wf.Base()))
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("could not find workflow %s; %s has no workflows", selector, repo.RepoFullName())
+		}
+		return nil, fmt.Errorf("could not find workflow %s; available workflows:\n  - %s", selector, strings.Join(candidates, "\n  - "))
+	case 1:
+		return &matches[0], nil
+	default:
+		candidates := []string{}
+		for _, wf := range matches {
+			candidates = append(candidates, fmt.Sprintf("%s (ID %d)", wf.Base(), wf.ID))
+		}
+		return nil, fmt.Errorf("workflow %s is ambiguous; specify one of its file names or IDs:\n  - %s", selector, strings.Join(candidates, "\n  - "))
+	}
+}
+
+// findTriggeredWorkflows returns the repository's active workflows whose
+// triggers, as declared at ref, satisfy triggeredBy. Dynamic workflows, such
+// as Dependabot's, have no file and are skipped, as are workflows whose file
+// can't be fetched or parsed, which are reported as a warning.
+func findTriggeredWorkflows(client *cliapi.Client, ios *iostreams.IOStreams, repo *ghRepo, ref string, triggeredBy func(*workflowTriggers) bool) ([]shared.Workflow, error) {
+	workflows, err := getWorkflows(client, repo.RepoHost(), repo.RepoFullName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflows: %w", err)
+	}
+
+	triggered := []shared.Workflow{}
+	for _, wf := range workflows {
+		if wf.Disabled() || !strings.HasPrefix(wf.Path, workflowsDir) {
+			continue
+		}
+
+		triggers, err := getWorkflowTriggers(client, repo, &wf, ref)
+		if err != nil {
+			fmt.Fprintf(ios.ErrOut, "%s skipping workflow %s: %s\n", ios.ColorScheme().WarningIcon(), wf.Path, err)
+			continue
+		}
+
+		if triggeredBy(triggers) {
+			triggered = append(triggered, wf)
+		}
+	}
+
+	return triggered, nil
+}
+
+// selectWorkflow prompts for one of the repository's active workflows whose
+// triggers, as declared at ref, satisfy triggeredBy.
+func selectWorkflow(p prompter, client *cliapi.Client, ios *iostreams.IOStreams, repo *ghRepo, ref string, triggeredBy func(*workflowTriggers) bool) (*shared.Workflow, error) {
+	candidates, err := findTriggeredWorkflows(client, ios, repo, ref, triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.New("no active workflows with a matching dispatch trigger found")
+	}
+
+	options := []string{}
+	for _, wf := range candidates {
+		options = append(options, fmt.Sprintf("%s (%s)", wf.Name, wf.Base()))
+	}
+
+	selected, err := p.Select("Select a workflow", "", options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &candidates[selected], nil
+}
+
+func getWorkflows(client *cliapi.Client, repoHost string, repoFullName string) ([]shared.Workflow, error) {
+	perPage := 100
+	page := 1
+	workflows := []shared.Workflow{}
+
+	for {
+		result := shared.WorkflowsPayload{}
+		path := fmt.Sprintf("repos/%s/actions/workflows?per_page=%d&page=%d", repoFullName, perPage, page)
+		err := client.REST(repoHost, "GET", path, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		workflows = append(workflows, result.Workflows...)
+		if len(result.Workflows) < perPage {
+			break
+		}
+
+		page++
+	}
+
+	return workflows, nil
+}
@@ -0,0 +1,167 @@
+package dispatch
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+
+	cliapi "github.com/cli/cli/v2/api"
+	"github.com/cli/cli/v2/pkg/httpmock"
+	"github.com/cli/cli/v2/pkg/iostreams"
+	ghprompter "github.com/cli/go-gh/v2/pkg/prompter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectWorkflow(t *testing.T) {
+	contentResponse := func(content string) httpmock.Responder {
+		return httpmock.StringResponse(fmt.Sprintf(`{"content": "%s"}`, base64.StdEncoding.EncodeToString([]byte(content))))
+	}
+
+	reg := &httpmock.Registry{}
+	defer reg.Verify(t)
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows"),
+		httpmock.StringResponse(`{
+			"total_count": 6,
+			"workflows": [{
+				"id": 1,
+				"name": "Goodbye",
+				"path": ".github/workflows/goodbye.yaml",
+				"state": "active"
+			}, {
+				"id": 2,
+				"name": "Hello",
+				"path": ".github/workflows/hello.yaml",
+				"state": "active"
+			}, {
+				"id": 3,
+				"name": "Farewell",
+				"path": ".github/workflows/farewell.yaml",
+				"state": "active"
+			}, {
+				"id": 4,
+				"name": "Disabled",
+				"path": ".github/workflows/disabled.yaml",
+				"state": "disabled_manually"
+			}, {
+				"id": 5,
+				"name": "Dependabot Updates",
+				"path": "dynamic/dependabot/dependabot-updates",
+				"state": "active"
+			}, {
+				"id": 6,
+				"name": "Broken",
+				"path": ".github/workflows/broken.yaml",
+				"state": "active"
+			}]
+		}`))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/goodbye.yaml"),
+		contentResponse("on: workflow_dispatch"))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/hello.yaml"),
+		contentResponse("on:\n  repository_dispatch:\n    types: [hello]"))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/farewell.yaml"),
+		contentResponse("on:\n  repository_dispatch:\n    types: [goodbye]"))
+	reg.Register(
+		httpmock.REST("GET", "repos/OWNER/REPO/contents/.github/workflows/broken.yaml"),
+		httpmock.StatusStringResponse(http.StatusNotFound, `{"message": "Not Found"}`))
+
+	p := ghprompter.NewMock(t)
+	p.RegisterSelect("Select a workflow", []string{"Hello (hello.yaml)"}, func(_, _ string, _ []string) (int, error) {
+		return 0, nil
+	})
+
+	client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+	ios, _, _, stderr := iostreams.Test()
+	repo := &ghRepo{Owner: "OWNER", Name: "REPO"}
+
+	wf, err := selectWorkflow(p, client, ios, repo, "", func(triggers *workflowTriggers) bool {
+		return triggers.triggeredByRepositoryDispatch("hello")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), wf.ID)
+
+	// The dynamic workflow isn't fetched, and the broken one is skipped.
+	assert.Equal(t, "! skipping workflow .github/workflows/broken.yaml: failed to get workflow file .github/workflows/broken.yaml: HTTP 404 (https://api.github.com/repos/OWNER/REPO/contents/.github/workflows/broken.yaml)\n", stderr.String())
+}
+
+func TestResolveWorkflow(t *testing.T) {
+	workflowsResponse := `{
+		"total_count": 3,
+		"workflows": [{
+			"id": 1,
+			"name": "Hello",
+			"path": ".github/workflows/hello.yaml"
+		}, {
+			"id": 2,
+			"name": "Goodbye",
+			"path": ".github/workflows/goodbye.yaml"
+		}, {
+			"id": 3,
+			"name": "Goodbye",
+			"path": ".github/workflows/farewell.yaml"
+		}]
+	}`
+
+	tests := []struct {
+		name     string
+		selector string
+		wantID   int64
+		wantErr  bool
+		errMsg   string
+	}{
+		{
+			name:     "file name",
+			selector: "hello.yaml",
+			wantID:   1,
+		}, {
+			name:     "ID",
+			selector: "3",
+			wantID:   3,
+		}, {
+			name:     "name",
+			selector: "Hello",
+			wantID:   1,
+		}, {
+			name:     "ambiguous name",
+			selector: "Goodbye",
+			wantErr:  true,
+			errMsg: `workflow Goodbye is ambiguous; specify one of its file names or IDs:
+  - goodbye.yaml (ID 2)
+  - farewell.yaml (ID 3)`,
+		}, {
+			name:     "not found",
+			selector: "Hi",
+			wantErr:  true,
+			errMsg: `could not find workflow Hi; available workflows:
+  - Hello (hello.yaml)
+  - Goodbye (goodbye.yaml)
+  - Goodbye (farewell.yaml)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &httpmock.Registry{}
+			defer reg.Verify(t)
+			reg.Register(
+				httpmock.REST("GET", "repos/OWNER/REPO/actions/workflows"),
+				httpmock.StringResponse(workflowsResponse))
+
+			client := cliapi.NewClientFromHTTP(&http.Client{Transport: reg})
+			repo := &ghRepo{Owner: "OWNER", Name: "REPO"}
+
+			wf, err := resolveWorkflow(client, repo, tt.selector)
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.errMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantID, wf.ID)
+		})
+	}
+}